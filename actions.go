@@ -0,0 +1,110 @@
+package qqbotapi
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ActionSpec describes the parameters accepted by an action.
+type ActionSpec struct {
+	Required []string
+	Optional []string
+}
+
+// has returns if the param is accepted by the action.
+func (spec ActionSpec) has(param string) bool {
+	return containsString(spec.Required, param) || containsString(spec.Optional, param)
+}
+
+// OneBotActions is a table of OneBot v11 (CQ HTTP API) actions and their parameters,
+// which CheckChattable checks configs against.
+//
+// Add your own entries for custom actions provided by the backend you use.
+var OneBotActions = map[string]ActionSpec{
+	"send_private_msg":        {Required: []string{"user_id", "message"}, Optional: []string{"group_id", "auto_escape"}},
+	"send_group_msg":          {Required: []string{"group_id", "message"}, Optional: []string{"auto_escape"}},
+	"send_discuss_msg":        {Required: []string{"discuss_id", "message"}, Optional: []string{"auto_escape"}},
+	"send_msg":                {Required: []string{"message"}, Optional: []string{"message_type", "user_id", "group_id", "discuss_id", "auto_escape"}},
+	"delete_msg":              {Required: []string{"message_id"}},
+	"get_msg":                 {Required: []string{"message_id"}},
+	"get_forward_msg":         {Required: []string{"id"}, Optional: []string{"message_id"}},
+	"send_like":               {Required: []string{"user_id"}, Optional: []string{"times"}},
+	"set_group_kick":          {Required: []string{"group_id", "user_id"}, Optional: []string{"reject_add_request"}},
+	"set_group_ban":           {Required: []string{"group_id", "user_id"}, Optional: []string{"duration"}},
+	"set_group_anonymous_ban": {Required: []string{"group_id"}, Optional: []string{"anonymous", "anonymous_flag", "flag", "duration"}},
+	"set_group_whole_ban":     {Required: []string{"group_id"}, Optional: []string{"enable"}},
+	"set_group_admin":         {Required: []string{"group_id", "user_id"}, Optional: []string{"enable"}},
+	"set_group_anonymous":     {Required: []string{"group_id"}, Optional: []string{"enable"}},
+	"set_group_card":          {Required: []string{"group_id", "user_id"}, Optional: []string{"card"}},
+	"set_group_name":          {Required: []string{"group_id", "group_name"}},
+	"set_group_portrait":      {Required: []string{"group_id", "file"}, Optional: []string{"cache"}},
+	"_send_group_notice":      {Required: []string{"group_id", "content"}, Optional: []string{"image"}},
+	"set_group_leave":         {Required: []string{"group_id"}, Optional: []string{"is_dismiss"}},
+	"set_discuss_leave":       {Required: []string{"discuss_id"}},
+	"set_group_special_title": {Required: []string{"group_id", "user_id"}, Optional: []string{"special_title", "duration"}},
+	"set_friend_add_request":  {Required: []string{"flag"}, Optional: []string{"approve", "remark"}},
+	"set_group_add_request":   {Required: []string{"flag"}, Optional: []string{"sub_type", "type", "approve", "reason"}},
+	"upload_private_file":     {Required: []string{"user_id", "file", "name"}},
+	"send_guild_channel_msg":  {Required: []string{"guild_id", "channel_id", "message"}},
+	"get_guild_list":          {},
+	"get_guild_channel_list":  {Required: []string{"guild_id"}, Optional: []string{"no_cache"}},
+	"get_guild_member_list":   {Required: []string{"guild_id"}, Optional: []string{"next_token"}},
+	"get_login_info":          {},
+	"get_stranger_info":       {Required: []string{"user_id"}, Optional: []string{"no_cache"}},
+	"get_friend_list":         {},
+	"get_group_info":          {Required: []string{"group_id"}, Optional: []string{"no_cache"}},
+	"get_group_list":          {},
+	"get_group_member_info":   {Required: []string{"group_id", "user_id"}, Optional: []string{"no_cache"}},
+	"get_group_member_list":   {Required: []string{"group_id"}},
+	"get_cookies":             {Optional: []string{"domain"}},
+	"get_csrf_token":          {},
+	"get_credentials":         {Optional: []string{"domain"}},
+	"get_record":              {Required: []string{"file", "out_format"}},
+	"get_image":               {Required: []string{"file"}},
+	"can_send_image":          {},
+	"can_send_record":         {},
+	"get_status":              {},
+	"get_version_info":        {},
+	"set_restart":             {Optional: []string{"delay"}},
+	"clean_cache":             {},
+	"get_updates":             {Optional: []string{"limit", "timeout"}},
+
+	// messages is a list of nodes, so that they are sent in a JSON body
+	"send_group_forward_msg":   {Required: []string{"group_id", "messages"}},
+	"send_private_forward_msg": {Required: []string{"user_id", "messages"}},
+}
+
+// CheckChattable checks the method and values of c against OneBotActions,
+// reporting an unknown action, missing required params and unknown params.
+func CheckChattable(c Chattable) error {
+	method := c.method()
+	spec, ok := OneBotActions[method]
+	if !ok {
+		return errors.New("unknown action " + method)
+	}
+	v, err := c.values()
+	if err != nil {
+		return err
+	}
+	problems := make([]string, 0)
+	for _, param := range spec.Required {
+		if _, ok := v[param]; !ok {
+			problems = append(problems, "missing "+param)
+		}
+	}
+	params := make([]string, 0, len(v))
+	for param := range v {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		if !spec.has(param) {
+			problems = append(problems, "unknown "+param)
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(method + ": " + strings.Join(problems, ", "))
+	}
+	return nil
+}
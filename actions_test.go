@@ -0,0 +1,70 @@
+package qqbotapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckChattable(t *testing.T) {
+	member := ChatMemberConfig{GroupID: 10000, UserID: 10000}
+	anonymous := ChatMemberConfig{GroupID: 10000, AnonymousFlag: "flag"}
+	request := HandleRequestConfig{RequestFlag: "flag", Approve: true}
+	for _, c := range []Chattable{
+		NewMessage(10000, "private", "hi"),
+		NewMessage(10000, "group", "hi"),
+		NewMessage(10000, "discuss", "hi"),
+		NewPrivateMessage(10000, "hi"),
+		PrivateMessageConfig{MessageConfig: NewMessage(10000, "private", "hi"), GroupID: 10000},
+		NewGroupMessage(10000, "hi"),
+		NewForwardMessage(10000, "group", NewForwardNode("nickname", 10000, "hi")),
+		NewForwardMessage(10000, "private", ForwardNode{MessageID: 1}),
+		DeleteMessageConfig{MessageID: 1},
+		LikeConfig{UserID: 10000, Times: 10},
+		KickChatMemberConfig{ChatMemberConfig: member, RejectAddRequest: true},
+		RestrictChatMemberConfig{ChatMemberConfig: member, Duration: time.Minute},
+		RestrictChatMemberConfig{ChatMemberConfig: anonymous, Duration: time.Minute},
+		PromoteChatMemberConfig{ChatMemberConfig: member, Enable: true},
+		SetChatMemberCardConfig{ChatMemberConfig: member, Card: "card"},
+		SetChatMemberTitleConfig{ChatMemberConfig: member, SpecialTitle: "title", Duration: time.Hour},
+		RestrictAllChatMembersConfig{GroupControlConfig{GroupID: 10000, Enable: true}},
+		EnableAnonymousChatConfig{GroupControlConfig{GroupID: 10000, Enable: true}},
+		SetGroupNameConfig{GroupID: 10000, GroupName: "group"},
+		SetGroupPortraitConfig{GroupID: 10000, File: []byte("portrait"), Cache: true},
+		SendGroupNoticeConfig{GroupID: 10000, Content: "notice", Image: "https://example.com/a.png"},
+		UploadPrivateFileConfig{UserID: 10000, File: "/tmp/a.txt", Name: "a.txt"},
+		GuildChannelMessageConfig{GuildID: "1", ChannelID: "2", Text: "hi"},
+		LeaveChatConfig{BaseChat: BaseChat{ChatID: 10000, ChatType: "group"}, IsDismiss: true},
+		LeaveChatConfig{BaseChat: BaseChat{ChatID: 10000, ChatType: "discuss"}},
+		HandleFriendRequestConfig{HandleRequestConfig: request, Remark: "remark"},
+		HandleGroupRequestConfig{HandleRequestConfig: request, Type: "add"},
+	} {
+		if err := CheckChattable(c); err != nil {
+			t.Errorf("CheckChattable failed: %T %v", c, err)
+		}
+	}
+}
+
+func TestRegisterAction(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"group_id": 10000, "user_id": 100000, "card": "card"})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	var getMember func(groupID int64, userID int64) (GroupMember, error)
+	err := bot.RegisterAction("get_group_member_info", func(args ...interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"group_id": args[0], "user_id": args[1]}, nil
+	}, GroupMember{}).Bind(&getMember)
+	if err != nil {
+		t.Fatalf("TestRegisterAction failed: %v", err)
+	}
+	member, err := getMember(10000, 100000)
+	if err == nil && member.UserID == 100000 && member.Card == "card" {
+		t.Log("TestRegisterAction passed")
+	} else {
+		t.Errorf("TestRegisterAction failed: %v %v", err, member)
+	}
+
+	var wrong func() (User, error)
+	if err := bot.Action("get_group_member_info").Bind(&wrong); err == nil {
+		t.Errorf("TestRegisterAction failed: binding wrong result type")
+	}
+}
@@ -0,0 +1,37 @@
+package qqbotapi
+
+import (
+	"github.com/catsworld/qq-bot-api/cqcode"
+)
+
+// arrayMessageConfig sends the message of a message config as an array of segments,
+// instead of a CQ string, see BotAPI.ArrayMessages.
+type arrayMessageConfig struct {
+	Chattable
+}
+
+// params returns Params of the config, whose message is an array of segments.
+func (config arrayMessageConfig) params() (Params, error) {
+	p, err := chattableParams(config.Chattable)
+	if err != nil {
+		return p, err
+	}
+	text, ok := p["message"].(string)
+	if !ok {
+		return p, nil
+	}
+	var message cqcode.Message
+	if autoEscape, _ := p["auto_escape"].(string); autoEscape == "true" {
+		message = cqcode.Message{&cqcode.Text{Text: text}}
+	} else {
+		message, _ = cqcode.ParseMessageFromString(text)
+	}
+	p["message"] = message.MessageSegments()
+	delete(p, "auto_escape")
+	return p, nil
+}
+
+// Validate checks the config if it implements Validator.
+func (config arrayMessageConfig) Validate() error {
+	return validate(config.Chattable)
+}
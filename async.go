@@ -0,0 +1,65 @@
+package qqbotapi
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// asyncConfig sends a Chattable with the _async action, which CQ HTTP responds
+// before handling it.
+type asyncConfig struct {
+	Chattable
+}
+
+// method returns the _async action of the config.
+func (config asyncConfig) method() string {
+	return config.Chattable.method() + "_async"
+}
+
+// params returns Params of the config.
+func (config asyncConfig) params() (Params, error) {
+	return chattableParams(config.Chattable)
+}
+
+// Validate checks the config if it implements Validator.
+func (config asyncConfig) Validate() error {
+	return validate(config.Chattable)
+}
+
+// DoAsync sends a Chattable item like Do with the _async action,
+// which returns once CQ HTTP accepts it, without waiting for the result.
+//
+// The async retcode 1 is not an error.
+func (bot *BotAPI) DoAsync(c Chattable) error {
+	return bot.DoAsyncWithContext(context.Background(), c)
+}
+
+// DoAsyncWithContext sends a Chattable item like DoAsync, which is aborted when ctx is done.
+func (bot *BotAPI) DoAsyncWithContext(ctx context.Context, c Chattable) error {
+	_, err := bot.DoWithContext(ctx, asyncConfig{c})
+	if IsAsync(err) {
+		return nil
+	}
+	return err
+}
+
+// SendAsync sends a Chattable item like Send with the _async action,
+// which returns once CQ HTTP accepts it, so that the message sent is unknown.
+//
+// Message transformers and the rate limiter are applied as with Send.
+func (bot *BotAPI) SendAsync(c Chattable) error {
+	return bot.SendAsyncWithContext(context.Background(), c)
+}
+
+// SendAsyncWithContext sends a Chattable item like SendAsync, which is aborted when ctx is done.
+func (bot *BotAPI) SendAsyncWithContext(ctx context.Context, c Chattable) error {
+	c, err := bot.prepareSend(ctx, c)
+	if err != nil {
+		return err
+	}
+	if err := bot.DoAsyncWithContext(ctx, c); err != nil {
+		return err
+	}
+	atomic.AddInt64(&bot.counters().messagesSent, 1)
+	return nil
+}
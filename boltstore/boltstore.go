@@ -0,0 +1,135 @@
+// Package boltstore provides the stores of qqbotapi backed by bbolt,
+// so that the state survives restarts of the bot without a database server.
+package boltstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/catsworld/qq-bot-api"
+	"github.com/catsworld/qq-bot-api/internal/storeutil"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	messagesBucket = []byte("messages")
+	likesBucket    = []byte("likes")
+)
+
+// Store is a qqbotapi.MessageStore and qqbotapi.LikeStore backed by bbolt.
+//
+// Messages are kept until they are deleted from the database.
+type Store struct {
+	db *bbolt.DB
+}
+
+// Open opens the database at path, creating it if it does not exist.
+func Open(path string) (*Store, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New creates a Store in an opened database.
+func New(db *bbolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{messagesBucket, likesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func key(id int64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(id))
+	return k
+}
+
+// Put saves a message.
+func (s *Store) Put(message *qqbotapi.Message) error {
+	data, err := storeutil.EncodeMessage(message)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(messagesBucket).Put(key(message.MessageID), data)
+	})
+}
+
+// Get returns the message with the MessageID, or nil if it is not found.
+func (s *Store) Get(messageID int64) (*qqbotapi.Message, error) {
+	var message *qqbotapi.Message
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(messagesBucket).Get(key(messageID))
+		if data == nil {
+			return nil
+		}
+		m, err := storeutil.DecodeMessage(data)
+		message = m
+		return err
+	})
+	return message, err
+}
+
+// PutLikeProgress saves the progress of a user, and deletes it if nothing remains.
+func (s *Store) PutLikeProgress(progress *qqbotapi.LikeProgress) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(likesBucket)
+		if progress.Remaining <= 0 {
+			return b.Delete(key(progress.UserID))
+		}
+		data, err := json.Marshal(progress)
+		if err != nil {
+			return err
+		}
+		return b.Put(key(progress.UserID), data)
+	})
+}
+
+// GetLikeProgress returns the progress of a user, or nil if it is not found.
+func (s *Store) GetLikeProgress(userID int64) (*qqbotapi.LikeProgress, error) {
+	var progress *qqbotapi.LikeProgress
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(likesBucket).Get(key(userID))
+		if data == nil {
+			return nil
+		}
+		progress = &qqbotapi.LikeProgress{}
+		return json.Unmarshal(data, progress)
+	})
+	return progress, err
+}
+
+// LikeProgresses returns all the unfinished progresses.
+func (s *Store) LikeProgresses() ([]*qqbotapi.LikeProgress, error) {
+	progresses := make([]*qqbotapi.LikeProgress, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(likesBucket).ForEach(func(k, data []byte) error {
+			var progress qqbotapi.LikeProgress
+			if err := json.Unmarshal(data, &progress); err != nil {
+				return err
+			}
+			progresses = append(progresses, &progress)
+			return nil
+		})
+	})
+	return progresses, err
+}
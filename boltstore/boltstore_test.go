@@ -0,0 +1,36 @@
+package boltstore
+
+import (
+	"github.com/catsworld/qq-bot-api"
+	"github.com/catsworld/qq-bot-api/cqcode"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "boltstore")
+	defer os.RemoveAll(dir)
+
+	s, err := Open(filepath.Join(dir, "bot.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	segments, _ := cqcode.ParseMessageFromString("hi[CQ:face,id=14]")
+	s.Put(&qqbotapi.Message{Message: &segments, MessageID: 12, Text: "hi[CQ:face,id=14]", Chat: &qqbotapi.Chat{ID: 10000, Type: "group"}, Time: 1500000000, MessageSeq: 100})
+	s.PutLikeProgress(&qqbotapi.LikeProgress{UserID: 10000, Remaining: 15})
+	s.PutLikeProgress(&qqbotapi.LikeProgress{UserID: 10001, Remaining: 5})
+	s.PutLikeProgress(&qqbotapi.LikeProgress{UserID: 10001})
+
+	message, err1 := s.Get(12)
+	missing, err2 := s.Get(13)
+	progresses, err3 := s.LikeProgresses()
+	if err1 == nil && err2 == nil && err3 == nil && missing == nil && len(*message.Message) == 2 && message.Chat.IsGroup() && message.Time == 1500000000 && message.MessageSeq == 100 && len(progresses) == 1 && progresses[0].Remaining == 15 {
+		t.Log("TestStore passed")
+	} else {
+		t.Errorf("TestStore failed: %v %v %v %v %v", err1, err2, err3, message, progresses)
+	}
+}
@@ -3,8 +3,12 @@
 package qqbotapi
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -12,13 +16,15 @@ import (
 	"github.com/catsworld/qq-bot-api/cqcode"
 	"github.com/mitchellh/mapstructure"
 	"golang.org/x/net/websocket"
+	"hash"
 	"io"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,15 +36,80 @@ type BotAPI struct {
 	Buffer      int    `json:"buffer"`
 	APIEndpoint string `json:"api_endpoint"`
 
-	Self              User                     `json:"-"`
-	Client            *http.Client             `json:"-"`
-	WSAPIClient       *websocket.Conn          `json:"-"`
-	WSEventClient     *websocket.Conn          `json:"-"`
-	WSPendingRequests map[int]chan APIResponse `json:"-"`
-	WSPendingMux      sync.Mutex               `json:"-"`
-	WSRequestTimeout  time.Duration            `json:"-"`
-	Echo              int                      `json:"-"`
-	EchoMux           sync.Mutex               `json:"-"`
+	Self             User            `json:"-"`
+	Client           *http.Client    `json:"-"`
+	WSAPIClient      *websocket.Conn `json:"-"`
+	WSEventClient    *websocket.Conn `json:"-"`
+	WSRequestTimeout time.Duration   `json:"-"`
+	// WSMaxBackoff is the max interval between attempts to reconnect a dropped websocket,
+	// which starts from 1 second and doubles. It is 1 minute if not set.
+	WSMaxBackoff time.Duration `json:"-"`
+	// WSUniversal uses the universal / websocket, which carries both events and API responses,
+	// instead of /api/ and /event/. WSAPIClient and WSEventClient are the same connection then.
+	WSUniversal bool `json:"-"`
+
+	// MessageStore, if set, keeps the messages in received updates.
+	MessageStore MessageStore `json:"-"`
+	// DisableCompression stops requesting gzip compressed responses over HTTP.
+	DisableCompression bool `json:"-"`
+	// ArrayMessages sends messages as arrays of segments instead of CQ strings.
+	// Set message_post_format of CQ HTTP to array to receive them as arrays too,
+	// both of which are parsed into cqcode.Message.
+	ArrayMessages bool `json:"-"`
+	// ResponseHook, if set, receives the body of every HTTP API response,
+	// which is truncated to ResponseCaptureLimit bytes.
+	ResponseHook func(endpoint string, body []byte) `json:"-"`
+	// PanicHandler, if set, is called when a panic is recovered in the update pipeline,
+	// e.g. in receiving loops and webhook handlers, including the handler of ListenForWebhookSync.
+	// Panics are logged with Logger anyway.
+	PanicHandler PanicHandler `json:"-"`
+	// Logger, if set, receives the logs of the bot instead of the log package.
+	// Debug logs are written only if Debug is set.
+	Logger Logger `json:"-"`
+	// LikeStore, if set, keeps the progress of LikeDaily.
+	LikeStore LikeStore `json:"-"`
+	// RateLimiter, if set, limits the rate of messages sent with MessageConfig,
+	// PrivateMessageConfig or GroupMessageConfig.
+	RateLimiter RateLimiter `json:"-"`
+	// InfoCacheTTL, if set, is how long the results of GetGroupMemberInfo, GetStrangerInfo
+	// and GetGroupList are cached in memory. Cached member info is dropped on notices of
+	// members joining, leaving, or changing card or admin.
+	InfoCacheTTL time.Duration `json:"-"`
+	// MentionAllIsToMe regards messages mentioning all members as directed to the bot in IsMessageToMe.
+	MentionAllIsToMe bool `json:"-"`
+	// ReplyIsToMe regards replies to messages of the bot as directed to the bot in IsMessageToMe.
+	ReplyIsToMe bool `json:"-"`
+	// SuperuserStore, if set, keeps the superusers of the bot, see RoleOf.
+	SuperuserStore SuperuserStore `json:"-"`
+
+	transformers []MessageTransformer
+	middlewares  []RequestMiddleware
+	useMux       sync.RWMutex // guards transformers and middlewares
+	failover     *failover
+	failoverMux  sync.Mutex // guards failover
+	cache        *infoCache
+	cacheOnce    sync.Once
+	actions      map[string]*Action
+	observers    []ErrorObserver
+	alerts       []*retCodeAlert
+	observerMux  sync.Mutex
+	stats        *botStats
+	statsOnce    sync.Once
+	likeRunning  map[int64]bool
+	likeMux      sync.Mutex
+	stopCtx      context.Context
+	stop         context.CancelFunc
+	stopMux      sync.Mutex
+	ext          *Extensions
+	extOnce      sync.Once
+
+	wsMux       sync.Mutex      // guards WSAPIClient and WSEventClient, which are replaced on reconnecting
+	requests    *requestTracker // requests over websocket waiting for responses
+	trackerOnce sync.Once
+	wsEvents    chan Update // events received over the universal websocket
+	closeCtx    context.Context
+	closeBot    context.CancelFunc
+	closeOnce   sync.Once
 }
 
 // NewBotAPI creates a new BotAPI instance.
@@ -69,9 +140,20 @@ func NewBotAPI(token string, api string, secret string) (*BotAPI, error) {
 // It requires a token, an API endpoint and a secret which you
 // set in Coolq HTTP API.
 func NewBotAPIWithClient(token string, api string, secret string) (*BotAPI, error) {
+	return NewBotAPIWithHTTPClient(token, api, secret, &http.Client{})
+}
+
+// NewBotAPIWithHTTPClient creates a new BotAPI instance like NewBotAPIWithClient,
+// sending requests with client, e.g. through a proxy or with timeouts.
+//
+// http.DefaultClient is used if client is nil.
+func NewBotAPIWithHTTPClient(token string, api string, secret string, client *http.Client) (*BotAPI, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
 	bot := &BotAPI{
 		Token:       token,
-		Client:      &http.Client{},
+		Client:      client,
 		Buffer:      100,
 		APIEndpoint: api,
 		Secret:      secret,
@@ -97,166 +179,313 @@ func NewBotAPIWithWSClient(token string, api string) (*BotAPI, error) {
 		Buffer:      100,
 		APIEndpoint: api,
 	}
-	var err error
-	// Dial /api/ ws
-	apiConfig, err := websocket.NewConfig(api+"/api/", "http://localhost/")
-	if err != nil {
-		return nil, errors.New("invalid websocket address")
+	if err := bot.dialWebSocket(); err != nil {
+		return nil, err
 	}
-	apiConfig.Header.Add("Authorization", fmt.Sprintf("Token %s", token))
-	bot.WSAPIClient, err = websocket.DialConfig(apiConfig)
+
+	self, err := bot.GetMe()
 	if err != nil {
-		return nil, errors.New("failed to dial cqhttp api websocket")
+		return nil, err
 	}
-	bot.debugLog("Dial /api/ ws", "dial cqhttp api websocket success")
-	// Dial /event/ ws
-	eventConfig, err := websocket.NewConfig(api+"/event/", "http://localhost/")
+
+	bot.Self = self
+
+	return bot, nil
+}
+
+// NewBotAPIWithUniversalWSClient creates a new BotAPI instance like NewBotAPIWithWSClient,
+// but over the universal / websocket instead of /api/ and /event/.
+func NewBotAPIWithUniversalWSClient(token string, api string) (*BotAPI, error) {
+	bot := &BotAPI{
+		Token:       token,
+		Buffer:      100,
+		APIEndpoint: api,
+		WSUniversal: true,
+	}
+	if err := bot.dialWebSocket(); err != nil {
+		return nil, err
+	}
+
+	self, err := bot.GetMe()
 	if err != nil {
-		return nil, errors.New("invalid websocket address")
+		return nil, err
 	}
-	eventConfig.Header.Add("Authorization", fmt.Sprintf("Token %s", token))
-	bot.WSEventClient, err = websocket.DialConfig(eventConfig)
+
+	bot.Self = self
+
+	return bot, nil
+}
+
+// NewLazyBotAPI creates a new BotAPI instance like NewBotAPI, but neither connects
+// to Coolq HTTP API nor fetches bot.Self, so that it does not fail if Coolq is not up yet.
+//
+// Call Init before using it.
+func NewLazyBotAPI(token string, api string, secret string) (*BotAPI, error) {
+	u, err := url.Parse(api)
 	if err != nil {
-		return nil, errors.New("failed to dial cqhttp event websocket")
+		return nil, err
 	}
-	bot.debugLog("Dial /event/ ws", "dial cqhttp event websocket success")
+	bot := &BotAPI{
+		Token:       token,
+		Buffer:      100,
+		APIEndpoint: api,
+		Secret:      secret,
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+	case "http", "https":
+		bot.Client = &http.Client{}
+	default:
+		return nil, errors.New("bad api url scheme")
+	}
+	return bot, nil
+}
 
-	bot.WSPendingRequests = make(map[int]chan APIResponse)
-	bot.WSRequestTimeout = time.Second * 10
-	go func() {
-		for {
-			// get api response
-			resp := APIResponse{}
-			if err := websocket.JSON.Receive(bot.WSAPIClient, &resp); err != nil {
-				bot.debugLog("WS APIResponse", "failed to read apiresponse (%v)", err)
-				continue
-			}
-			echo, ok := resp.Echo.(float64)
-			if !ok {
-				continue
-			}
-			e := int(echo)
-			bot.WSPendingMux.Lock()
-			if ch, ok := bot.WSPendingRequests[e]; ok {
-				ch <- resp
-				close(ch)
-				delete(bot.WSPendingRequests, e)
-			}
-			bot.WSPendingMux.Unlock()
+// Init connects the websocket if it is not connected, and fetches bot.Self,
+// for BotAPI created by NewLazyBotAPI. It could be called again if it fails.
+func (bot *BotAPI) Init() error {
+	if bot.Client == nil && bot.apiConn() == nil {
+		if err := bot.dialWebSocket(); err != nil {
+			return err
 		}
-	}()
+	}
+	return bot.RefreshSelf()
+}
 
+// RefreshSelf fetches the login info, and updates bot.Self.
+func (bot *BotAPI) RefreshSelf() error {
 	self, err := bot.GetMe()
 	if err != nil {
-		return nil, err
+		return err
 	}
-
 	bot.Self = self
+	return nil
+}
 
-	return bot, nil
+// dialWebSocket dials the /api/ and /event/ websocket of bot.APIEndpoint,
+// or the universal / websocket if bot.WSUniversal is set, and starts receiving API responses.
+func (bot *BotAPI) dialWebSocket() error {
+	if bot.WSUniversal {
+		// Dial / ws
+		conn, err := bot.dialWS("/")
+		if err != nil {
+			return errors.New("failed to dial cqhttp universal websocket")
+		}
+		bot.debugLog("Dial / ws", "dial cqhttp universal websocket success")
+		bot.setWSConns(conn, conn)
+		bot.wsEvents = make(chan Update, bot.Buffer)
+		bot.WSRequestTimeout = time.Second * 10
+		go bot.receiveAPIResponses()
+		return nil
+	}
+	// Dial /api/ ws
+	api, err := bot.dialWS("/api/")
+	if err != nil {
+		return errors.New("failed to dial cqhttp api websocket")
+	}
+	bot.debugLog("Dial /api/ ws", "dial cqhttp api websocket success")
+	// Dial /event/ ws
+	event, err := bot.dialWS("/event/")
+	if err != nil {
+		api.Close()
+		return errors.New("failed to dial cqhttp event websocket")
+	}
+	bot.debugLog("Dial /event/ ws", "dial cqhttp event websocket success")
+	bot.setWSConns(api, event)
+
+	bot.WSRequestTimeout = time.Second * 10
+	go bot.receiveAPIResponses()
+
+	return nil
 }
 
 // MakeRequest makes a request to a specific endpoint with our token.
 func (bot *BotAPI) MakeRequest(endpoint string, params url.Values) (APIResponse, error) {
-	if bot.Client != nil {
-		return bot.makeHTTPRequest(endpoint, params)
-	} else {
-		return bot.makeWSRequest(endpoint, params)
-	}
+	return bot.MakeRequestWithContext(context.Background(), endpoint, params)
 }
 
-func (bot *BotAPI) makeHTTPRequest(endpoint string, params url.Values) (APIResponse, error) {
+// MakeRequestWithContext makes a request like MakeRequest, which is aborted when ctx is done,
+// e.g. to set a deadline for a call to a hung CQ HTTP.
+func (bot *BotAPI) MakeRequestWithContext(ctx context.Context, endpoint string, params url.Values) (APIResponse, error) {
+	return bot.MakeRequestWithParams(ctx, endpoint, ParamsFromValues(params))
+}
 
-	method := fmt.Sprintf("%s/%s?access_token=%s", bot.APIEndpoint, endpoint, bot.Token)
+// MakeRequestWithParams makes a request like MakeRequestWithContext with Params,
+// whose types are kept over websocket.
+func (bot *BotAPI) MakeRequestWithParams(ctx context.Context, endpoint string, params Params) (APIResponse, error) {
+	var resp APIResponse
+	var err error
+	atomic.AddInt64(&bot.counters().apiCalls, 1)
+	resp, err = bot.invoke(ctx, endpoint, params)
+	if err != nil && !IsAsync(err) {
+		atomic.AddInt64(&bot.counters().apiErrors, 1)
+		bot.observeError(endpoint, resp, err)
+	}
+	return resp, err
+}
 
-	resp, err := bot.Client.PostForm(method, params)
+func (bot *BotAPI) makeHTTPRequest(ctx context.Context, endpoint string, params Params) (APIResponse, error) {
+	reqBody, err := params.body()
+	if err != nil {
+		return APIResponse{}, err
+	}
+	body, err := bot.openHTTPRequest(ctx, endpoint, reqBody)
 	if err != nil {
 		return APIResponse{}, err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
 	var apiResp APIResponse
-	bytes, err := bot.decodeAPIResponse(resp.Body, &apiResp)
+	data, err := bot.decodeAPIResponse(body, &apiResp)
+	if bot.ResponseHook != nil {
+		bot.ResponseHook(endpoint, data)
+	}
 	if err != nil {
 		return apiResp, err
 	}
 
-	bot.debugLog("MakeRequest", "%s resp: %s", endpoint, bytes)
+	bot.debugLog("MakeRequest", endpoint, string(data))
 
-	if apiResp.Status != "ok" {
-		return apiResp, errors.New(apiResp.Status + " " + strconv.Itoa(apiResp.RetCode))
-	}
-
-	return apiResp, nil
+	return apiResp, checkAPIResponse(apiResp)
 }
 
-func (bot *BotAPI) decodeAPIResponse(responseBody io.Reader, resp *APIResponse) (_ []byte, err error) {
-	if !bot.Debug {
-		dec := json.NewDecoder(responseBody)
-		err = dec.Decode(resp)
-		return
+// openHTTPRequest makes a request over HTTP and returns the decompressed response body.
+//
+// If failover is enabled, the request is made to the active endpoint, and made again
+// to the next healthy endpoint if the active one cannot be connected.
+func (bot *BotAPI) openHTTPRequest(ctx context.Context, endpoint string, reqBody requestBody) (io.ReadCloser, error) {
+	f := bot.failoverState()
+	if f == nil {
+		return bot.openHTTPRequestTo(ctx, bot.APIEndpoint, endpoint, reqBody)
+	}
+	base := f.active()
+	body, err := bot.openHTTPRequestTo(ctx, base, endpoint, reqBody)
+	if err != nil && ctx.Err() == nil {
+		if next := f.markDown(base); next != base && isDialError(err) {
+			bot.logger().Warn("API endpoint down, failing over", "from", base, "to", next, "error", err)
+			return bot.openHTTPRequestTo(ctx, next, endpoint, reqBody)
+		}
 	}
+	return body, err
+}
+
+// openHTTPRequestTo makes a request over HTTP to the API endpoint base.
+func (bot *BotAPI) openHTTPRequestTo(ctx context.Context, base string, endpoint string, reqBody requestBody) (io.ReadCloser, error) {
+	method := fmt.Sprintf("%s/%s?access_token=%s", base, endpoint, bot.Token)
 
-	// if debug, read reponse body
-	data, err := ioutil.ReadAll(responseBody)
+	req, err := http.NewRequest("POST", method, bytes.NewReader(reqBody.data))
 	if err != nil {
-		return
+		return nil, err
+	}
+	req.Header.Set("Content-Type", reqBody.contentType)
+	// Accept-Encoding is set explicitly, so that the response is decompressed
+	// here regardless of the transport of bot.Client.
+	if bot.DisableCompression {
+		req.Header.Set("Accept-Encoding", "identity")
+	} else {
+		req.Header.Set("Accept-Encoding", "gzip")
 	}
 
-	err = json.Unmarshal(data, resp)
+	resp, err := bot.Client.Do(req.WithContext(ctx))
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	return data, nil
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return gzipReadCloser{Reader: gz, body: resp.Body}, nil
+	}
+	return resp.Body, nil
 }
 
-func (bot *BotAPI) makeWSRequest(endpoint string, params url.Values) (APIResponse, error) {
-	bot.EchoMux.Lock()
-	bot.Echo++
-	echo := bot.Echo
-	bot.EchoMux.Unlock()
-	p := make(map[string]interface{})
-	if params != nil {
-		for k, vs := range params {
-			if len(vs) != 0 {
-				p[k] = vs[0]
-			}
+// gzipReadCloser closes both the gzip reader and the underlying body.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (r gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.body.Close()
+}
+
+// ResponseCaptureLimit is the max number of bytes of a response body captured
+// for debug logging and BotAPI.ResponseHook.
+var ResponseCaptureLimit = 64 << 10
+
+// decodeAPIResponse decodes the response, and returns the captured body
+// if debug logging or BotAPI.ResponseHook is enabled.
+func (bot *BotAPI) decodeAPIResponse(responseBody io.Reader, resp *APIResponse) ([]byte, error) {
+	if !bot.Debug && bot.ResponseHook == nil {
+		return nil, json.NewDecoder(responseBody).Decode(resp)
+	}
+
+	buf := &cappedBuffer{limit: ResponseCaptureLimit}
+	err := json.NewDecoder(io.TeeReader(responseBody, buf)).Decode(resp)
+	return buf.Bytes(), err
+}
+
+// cappedBuffer is a buffer which keeps only the first limit bytes written,
+// and never fails writing.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - b.Len(); n > 0 {
+		if len(p) > n {
+			b.Buffer.Write(p[:n])
+		} else {
+			b.Buffer.Write(p)
 		}
 	}
-	req := WebSocketRequest{
-		Echo:   echo,
-		Action: endpoint,
-		Params: p,
+	return len(p), nil
+}
+
+func (bot *BotAPI) makeWSRequest(ctx context.Context, endpoint string, params Params) (APIResponse, error) {
+	if params == nil {
+		params = Params{}
 	}
-	ch := make(chan APIResponse)
-	bot.WSPendingRequests[echo] = ch
-	err := websocket.JSON.Send(bot.WSAPIClient, req)
-	if err != nil {
-		delete(bot.WSPendingRequests, echo)
+	req, ch := bot.tracker().add(endpoint, params)
+	echo := req.Echo.(int)
+	if err := bot.sendWS(ctx, req); err != nil {
+		bot.tracker().remove(echo)
 		return APIResponse{}, err
 	}
-	t := time.After(bot.WSRequestTimeout)
+	t := time.NewTimer(bot.WSRequestTimeout)
+	defer t.Stop()
 	select {
-	case resp := <-ch:
-		return resp, nil
-	case <-t:
-		bot.WSPendingMux.Lock()
-		delete(bot.WSPendingRequests, echo)
-		close(ch)
-		bot.WSPendingMux.Unlock()
+	case res := <-ch:
+		if res.err != nil {
+			return APIResponse{}, res.err
+		}
+		return res.resp, checkAPIResponse(res.resp)
+	case <-t.C:
+		bot.tracker().remove(echo)
 		return APIResponse{}, errors.New("request timeout")
+	case <-ctx.Done():
+		bot.tracker().remove(echo)
+		return APIResponse{}, ctx.Err()
 	}
 }
 
-func (bot *BotAPI) makeMessageRequest(endpoint string, params url.Values) (Message, error) {
-	resp, err := bot.MakeRequest(endpoint, params)
+func (bot *BotAPI) makeMessageRequest(ctx context.Context, endpoint string, params Params) (Message, error) {
+	resp, err := bot.MakeRequestWithParams(ctx, endpoint, params)
 	if err != nil {
 		return Message{}, err
 	}
 
+	atomic.AddInt64(&bot.counters().messagesSent, 1)
+
 	var message Message
-	json.Unmarshal(resp.Data, &message)
+	if err := decodeData(endpoint, resp.Data, &message); err != nil {
+		return Message{}, err
+	}
 
 	bot.debugLog(endpoint, params, message)
 
@@ -275,7 +504,9 @@ func (bot *BotAPI) GetMe() (User, error) {
 	}
 
 	var user User
-	json.Unmarshal(resp.Data, &user)
+	if err := decodeData("get_login_info", resp.Data, &user); err != nil {
+		return User{}, err
+	}
 
 	bot.debugLog("GetMe", nil, user)
 
@@ -284,6 +515,9 @@ func (bot *BotAPI) GetMe() (User, error) {
 
 // GetStrangerInfo fetches a stranger's user info.
 func (bot *BotAPI) GetStrangerInfo(userID int64) (User, error) {
+	if user, ok := bot.cachedStranger(userID); ok {
+		return user, nil
+	}
 	v := url.Values{}
 	v.Add("user_id", strconv.FormatInt(userID, 10))
 	resp, err := bot.MakeRequest("get_stranger_info", v)
@@ -291,76 +525,520 @@ func (bot *BotAPI) GetStrangerInfo(userID int64) (User, error) {
 		return User{}, err
 	}
 	var user User
-	json.Unmarshal(resp.Data, &user)
+	if err := decodeData("get_stranger_info", resp.Data, &user); err != nil {
+		return User{}, err
+	}
+	bot.cacheStranger(user)
 
 	bot.debugLog("GetStrangerInfo", nil, user)
 
 	return user, nil
 }
 
-// GetGroupMemberInfo fetches a group member's user info.
+// GetGroupMemberInfo fetches a group member's info.
 //
 // Using cache may result in not updating in time, but will be responded faster
-func (bot *BotAPI) GetGroupMemberInfo(groupID int64, userID int64, noCache bool) (User, error) {
+func (bot *BotAPI) GetGroupMemberInfo(groupID int64, userID int64, noCache bool) (GroupMember, error) {
+	if member, ok := bot.cachedMember(groupID, userID); !noCache && ok {
+		return member, nil
+	}
 	v := url.Values{}
 	v.Add("group_id", strconv.FormatInt(groupID, 10))
 	v.Add("user_id", strconv.FormatInt(userID, 10))
 	v.Add("no_cache", strconv.FormatBool(noCache))
 	resp, err := bot.MakeRequest("get_group_member_info", v)
 	if err != nil {
-		return User{}, err
+		return GroupMember{}, err
 	}
-	var user User
-	json.Unmarshal(resp.Data, &user)
+	var member GroupMember
+	if err := decodeData("get_group_member_info", resp.Data, &member); err != nil {
+		return GroupMember{}, err
+	}
+	bot.cacheMember(member)
 
-	bot.debugLog("GetGroupMemberInfo", nil, user)
+	bot.debugLog("GetGroupMemberInfo", nil, member)
 
-	return user, nil
+	return member, nil
 }
 
-// GetGroupMemberList fetches a group all member's user info.
+// GetGroupMemberList fetches a group all member's info.
 //
 // This information might be not full or accurate enough.
-func (bot *BotAPI) GetGroupMemberList(groupID int64) ([]User, error) {
+func (bot *BotAPI) GetGroupMemberList(groupID int64) ([]GroupMember, error) {
 	v := url.Values{}
 	v.Add("group_id", strconv.FormatInt(groupID, 10))
 	resp, err := bot.MakeRequest("get_group_member_list", v)
 	if err != nil {
 		return nil, err
 	}
-	users := make([]User, 0)
-	json.Unmarshal(resp.Data, &users)
+	members := make([]GroupMember, 0)
+	if err := decodeData("get_group_member_list", resp.Data, &members); err != nil {
+		return nil, err
+	}
 
-	bot.debugLog("GetGroupMemberInfo", nil, users)
+	bot.debugLog("GetGroupMemberList", nil, members)
 
-	return users, nil
+	return members, nil
 }
 
 // GetGroupList fetches all groups
 func (bot *BotAPI) GetGroupList() ([]Group, error) {
+	if groups, ok := bot.cachedGroups(); ok {
+		return groups, nil
+	}
 	v := url.Values{}
 	resp, err := bot.MakeRequest("get_group_list", v)
 	if err != nil {
-		return nil, err
+		return nil, err
+	}
+	groups := make([]Group, 0)
+	if err := decodeData("get_group_list", resp.Data, &groups); err != nil {
+		return nil, err
+	}
+	bot.cacheGroups(groups)
+
+	bot.debugLog("GetGroupList", nil, groups)
+
+	return groups, nil
+}
+
+// GetGroupInfo fetches the info of a group, including its member counts.
+//
+// Using cache may result in not updating in time, but will be responded faster
+func (bot *BotAPI) GetGroupInfo(groupID int64, noCache bool) (Group, error) {
+	v := url.Values{}
+	v.Add("group_id", strconv.FormatInt(groupID, 10))
+	v.Add("no_cache", strconv.FormatBool(noCache))
+	resp, err := bot.MakeRequest("get_group_info", v)
+	if err != nil {
+		return Group{}, err
+	}
+	var group Group
+	if err := decodeData("get_group_info", resp.Data, &group); err != nil {
+		return Group{}, err
+	}
+
+	bot.debugLog("GetGroupInfo", nil, group)
+
+	return group, nil
+}
+
+// GetGroupAtAllRemain fetches if the bot could @all in a group and the remaining quota,
+// which is a go-cqhttp extension.
+func (bot *BotAPI) GetGroupAtAllRemain(groupID int64) (AtAllRemain, error) {
+	v := url.Values{}
+	v.Add("group_id", strconv.FormatInt(groupID, 10))
+	resp, err := bot.MakeRequest("get_group_at_all_remain", v)
+	if err != nil {
+		return AtAllRemain{}, err
+	}
+	var remain AtAllRemain
+	if err := decodeData("get_group_at_all_remain", resp.Data, &remain); err != nil {
+		return AtAllRemain{}, err
+	}
+
+	bot.debugLog("GetGroupAtAllRemain", nil, remain)
+
+	return remain, nil
+}
+
+// GetGroupNotices fetches the notices (公告) of a group, which is a go-cqhttp extension.
+func (bot *BotAPI) GetGroupNotices(groupID int64) ([]GroupNotice, error) {
+	v := url.Values{}
+	v.Add("group_id", strconv.FormatInt(groupID, 10))
+	resp, err := bot.MakeRequest("_get_group_notice", v)
+	if err != nil {
+		return nil, err
+	}
+	notices := make([]GroupNotice, 0)
+	if err := decodeData("_get_group_notice", resp.Data, &notices); err != nil {
+		return nil, err
+	}
+
+	bot.debugLog("GetGroupNotices", nil, notices)
+
+	return notices, nil
+}
+
+// GetGroupSystemMsg fetches the pending invitations for the bot to join groups, and requests
+// to join groups managed by the bot, which is a go-cqhttp extension.
+//
+// They could be handled later with HandleGroupRequest, using their Flag and SubType.
+func (bot *BotAPI) GetGroupSystemMsg() (GroupSystemMsg, error) {
+	resp, err := bot.MakeRequest("get_group_system_msg", url.Values{})
+	if err != nil {
+		return GroupSystemMsg{}, err
+	}
+	var msg GroupSystemMsg
+	if err := decodeData("get_group_system_msg", resp.Data, &msg); err != nil {
+		return GroupSystemMsg{}, err
+	}
+	for i := range msg.InvitedRequests {
+		msg.InvitedRequests[i].SubType = "invite"
+	}
+	for i := range msg.JoinRequests {
+		msg.JoinRequests[i].SubType = "add"
+	}
+
+	bot.debugLog("GetGroupSystemMsg", nil, msg)
+
+	return msg, nil
+}
+
+// GetFriendList fetches all friends, whose Remark is populated.
+func (bot *BotAPI) GetFriendList() ([]User, error) {
+	v := url.Values{}
+	resp, err := bot.MakeRequest("get_friend_list", v)
+	if err != nil {
+		return nil, err
+	}
+	friends := make([]User, 0)
+	if err := decodeData("get_friend_list", resp.Data, &friends); err != nil {
+		return nil, err
+	}
+
+	bot.debugLog("GetFriendList", nil, friends)
+
+	return friends, nil
+}
+
+// GetUnidirectionalFriendList fetches the users who have added the bot as a friend
+// but not been added back, whose Source is populated. It is a go-cqhttp extension.
+func (bot *BotAPI) GetUnidirectionalFriendList() ([]User, error) {
+	resp, err := bot.MakeRequest("get_unidirectional_friend_list", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	friends := make([]User, 0)
+	if err := decodeData("get_unidirectional_friend_list", resp.Data, &friends); err != nil {
+		return nil, err
+	}
+
+	bot.debugLog("GetUnidirectionalFriendList", nil, friends)
+
+	return friends, nil
+}
+
+// GetOnlineClients fetches the other devices logged in with the account of the bot,
+// which is a go-cqhttp extension.
+func (bot *BotAPI) GetOnlineClients(noCache bool) ([]Device, error) {
+	v := url.Values{}
+	v.Add("no_cache", strconv.FormatBool(noCache))
+	resp, err := bot.MakeRequest("get_online_clients", v)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Clients []Device `json:"clients"`
+	}
+	if err := decodeData("get_online_clients", resp.Data, &data); err != nil {
+		return nil, err
+	}
+	if data.Clients == nil {
+		data.Clients = make([]Device, 0)
+	}
+
+	bot.debugLog("GetOnlineClients", nil, data.Clients)
+
+	return data.Clients, nil
+}
+
+// GetMessage fetches a message by its MessageID, e.g. a message recalled or replied to.
+func (bot *BotAPI) GetMessage(messageID int64) (Message, error) {
+	v := url.Values{}
+	v.Add("message_id", strconv.FormatInt(messageID, 10))
+	resp, err := bot.MakeRequest("get_msg", v)
+	if err != nil {
+		return Message{}, err
+	}
+	var data messageData
+	if err := decodeData("get_msg", resp.Data, &data); err != nil {
+		return Message{}, err
+	}
+	message := data.message()
+
+	bot.debugLog("GetMessage", nil, message)
+
+	return message, nil
+}
+
+// GetGroupMessageHistory fetches messages in a group before the message of messageSeq,
+// or the latest messages if messageSeq is 0.
+//
+// Pass the MessageSeq of the earliest message returned to fetch the messages before,
+// e.g. to backfill the context after restarts.
+//
+// It is provided by go-cqhttp and NTQQ based implementations,
+// see also Ext().GetGroupMessageHistory for NapCat and LLOneBot.
+func (bot *BotAPI) GetGroupMessageHistory(groupID int64, messageSeq int64) ([]Message, error) {
+	params := map[string]interface{}{
+		"group_id": groupID,
+	}
+	if messageSeq != 0 {
+		params["message_seq"] = messageSeq
+	}
+	return bot.messageHistory("get_group_msg_history", params)
+}
+
+// GetFriendMessageHistory fetches messages in a private chat before the message of startSeq,
+// or the latest messages if startSeq is 0.
+//
+// It is provided by NTQQ based implementations.
+func (bot *BotAPI) GetFriendMessageHistory(userID int64, startSeq int64) ([]Message, error) {
+	params := map[string]interface{}{
+		"user_id": userID,
+	}
+	if startSeq != 0 {
+		params["message_seq"] = startSeq
+	}
+	return bot.messageHistory("get_friend_msg_history", params)
+}
+
+// messageHistory fetches and parses messages of a history action.
+func (bot *BotAPI) messageHistory(action string, params map[string]interface{}) ([]Message, error) {
+	var history struct {
+		Messages []messageData `json:"messages"`
+	}
+	if err := bot.DoInto(GenericConfig{Action: action, Params: params}, &history); err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(history.Messages))
+	for _, data := range history.Messages {
+		messages = append(messages, data.message())
+	}
+
+	bot.debugLog(action, nil, messages)
+
+	return messages, nil
+}
+
+// GetImage fetches the image of file, which is the file of a received cqcode.Image,
+// returning where CQ HTTP has saved it.
+func (bot *BotAPI) GetImage(file string) (MediaFile, error) {
+	v := url.Values{}
+	v.Add("file", file)
+	return bot.getMediaFile("get_image", v)
+}
+
+// GetRecord fetches the record of file, which is the file of a received cqcode.Record,
+// converted to outFormat, e.g. "mp3", "amr", "wma", "m4a", "spx", "ogg", "wav" or "flac".
+//
+// Converting requires ffmpeg installed on the host of CQ HTTP.
+func (bot *BotAPI) GetRecord(file string, outFormat string) (MediaFile, error) {
+	v := url.Values{}
+	v.Add("file", file)
+	v.Add("out_format", outFormat)
+	return bot.getMediaFile("get_record", v)
+}
+
+// getMediaFile makes a request of action returning a MediaFile.
+func (bot *BotAPI) getMediaFile(action string, v url.Values) (MediaFile, error) {
+	resp, err := bot.MakeRequest(action, v)
+	if err != nil {
+		return MediaFile{}, err
+	}
+	var file MediaFile
+	if err := decodeData(action, resp.Data, &file); err != nil {
+		return MediaFile{}, err
+	}
+
+	bot.debugLog(action, nil, file)
+
+	return file, nil
+}
+
+// CanSendImage returns if images could be sent, e.g. to fall back to text if not.
+func (bot *BotAPI) CanSendImage() (bool, error) {
+	return bot.canSend("can_send_image")
+}
+
+// CanSendRecord returns if records could be sent, e.g. to fall back to text if not.
+func (bot *BotAPI) CanSendRecord() (bool, error) {
+	return bot.canSend("can_send_record")
+}
+
+// canSend makes a request of action returning if something could be sent.
+func (bot *BotAPI) canSend(action string) (bool, error) {
+	resp, err := bot.MakeRequest(action, url.Values{})
+	if err != nil {
+		return false, err
+	}
+	var data struct {
+		Yes bool `json:"yes"`
+	}
+	if err := decodeData(action, resp.Data, &data); err != nil {
+		return false, err
+	}
+	return data.Yes, nil
+}
+
+// GetStatus fetches the running status of CQ HTTP, e.g. for health checks.
+func (bot *BotAPI) GetStatus() (Status, error) {
+	resp, err := bot.MakeRequest("get_status", url.Values{})
+	if err != nil {
+		return Status{}, err
+	}
+	var status Status
+	if err := decodeData("get_status", resp.Data, &status); err != nil {
+		return Status{}, err
+	}
+
+	bot.debugLog("GetStatus", nil, status)
+
+	return status, nil
+}
+
+// GetVersionInfo fetches the version of the implementation,
+// e.g. to tell go-cqhttp from other OneBot implementations.
+func (bot *BotAPI) GetVersionInfo() (VersionInfo, error) {
+	resp, err := bot.MakeRequest("get_version_info", url.Values{})
+	if err != nil {
+		return VersionInfo{}, err
+	}
+	var version VersionInfo
+	if err := decodeData("get_version_info", resp.Data, &version); err != nil {
+		return VersionInfo{}, err
+	}
+
+	bot.debugLog("GetVersionInfo", nil, version)
+
+	return version, nil
+}
+
+// GetCookies fetches the cookies of the bot for domain, e.g. "qun.qq.com",
+// to call QQ web interfaces.
+func (bot *BotAPI) GetCookies(domain string) (string, error) {
+	v := url.Values{}
+	if domain != "" {
+		v.Add("domain", domain)
+	}
+	resp, err := bot.MakeRequest("get_cookies", v)
+	if err != nil {
+		return "", err
+	}
+	var credentials Credentials
+	if err := decodeData("get_cookies", resp.Data, &credentials); err != nil {
+		return "", err
+	}
+	return credentials.Cookies, nil
+}
+
+// GetCSRFToken fetches the CSRF token of the bot, which is the bkn param of QQ web interfaces.
+func (bot *BotAPI) GetCSRFToken() (int64, error) {
+	resp, err := bot.MakeRequest("get_csrf_token", url.Values{})
+	if err != nil {
+		return 0, err
+	}
+	var credentials Credentials
+	if err := decodeData("get_csrf_token", resp.Data, &credentials); err != nil {
+		return 0, err
+	}
+	return credentials.CSRFToken, nil
+}
+
+// GetCredentials fetches both the cookies for domain and the CSRF token of the bot.
+func (bot *BotAPI) GetCredentials(domain string) (Credentials, error) {
+	v := url.Values{}
+	if domain != "" {
+		v.Add("domain", domain)
+	}
+	resp, err := bot.MakeRequest("get_credentials", v)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var credentials Credentials
+	if err := decodeData("get_credentials", resp.Data, &credentials); err != nil {
+		return Credentials{}, err
+	}
+	return credentials, nil
+}
+
+// DownloadFile makes CQ HTTP download the file of fileURL with threadCount threads and headers,
+// returning the path of the file on the host of CQ HTTP, which is a go-cqhttp extension.
+//
+// It is useful to upload a large remote file, e.g. with upload_group_file.
+func (bot *BotAPI) DownloadFile(fileURL string, threadCount int, headers map[string]string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for k, v := range headers {
+		lines = append(lines, k+"="+v)
+	}
+	sort.Strings(lines)
+	v := make(Params)
+	v["url"] = fileURL
+	v["thread_count"] = threadCount
+	if len(lines) > 0 {
+		v["headers"] = strings.Join(lines, "\r\n")
+	}
+	resp, err := bot.MakeRequestWithParams(context.Background(), "download_file", v)
+	if err != nil {
+		return "", err
 	}
-	groups := make([]Group, 0)
-	json.Unmarshal(resp.Data, &groups)
+	var file MediaFile
+	if err := decodeData("download_file", resp.Data, &file); err != nil {
+		return "", err
+	}
+	return file.File, nil
+}
 
-	bot.debugLog("GetGroupList", nil, groups)
+// Download makes CQ HTTP download the offline file with DownloadFile,
+// and returns the absolute path of the file downloaded.
+func (f *OfflineFile) Download(bot *BotAPI) (string, error) {
+	return bot.DownloadFile(f.URL, 1, nil)
+}
 
-	return groups, nil
+// Quoted returns the message replied to, if the message contains a reply segment.
+//
+// The message is looked up in bot.MessageStore first, then fetched with get_msg.
+// Nil will be returned if the message is not a reply.
+func (m Message) Quoted(bot *BotAPI) (*Message, error) {
+	if m.Message == nil {
+		return nil, nil
+	}
+	for _, media := range *m.Message {
+		reply, ok := media.(*cqcode.Reply)
+		if !ok {
+			continue
+		}
+		if bot.MessageStore != nil {
+			quoted, err := bot.MessageStore.Get(reply.ID)
+			if err == nil && quoted != nil {
+				atomic.AddInt64(&bot.counters().storeHits, 1)
+				return quoted, nil
+			}
+			atomic.AddInt64(&bot.counters().storeMisses, 1)
+		}
+		quoted, err := bot.GetMessage(reply.ID)
+		if err != nil {
+			return nil, err
+		}
+		if bot.MessageStore != nil {
+			bot.MessageStore.Put(&quoted)
+		}
+		return &quoted, nil
+	}
+	return nil, nil
 }
 
 // IsMessageToMe returns true if message directed to this bot.
 //
+// A message mentioning the bot is directed to it. So is a message mentioning all members
+// if bot.MentionAllIsToMe is set, and a reply to a message of the bot if bot.ReplyIsToMe is set,
+// for which the message replied to is looked up with Quoted.
+//
 // It requires the Message.
 func (bot *BotAPI) IsMessageToMe(message Message) bool {
-	for _, media := range *message.Message {
-		at, ok := media.(*cqcode.At)
-		if !ok {
-			continue
-		}
-		if at.QQ == strconv.FormatInt(bot.Self.ID, 10) {
+	if message.Message == nil {
+		return false
+	}
+	if message.MentionsUser(bot.Self.ID) {
+		return true
+	}
+	if bot.MentionAllIsToMe && message.MentionsAll() {
+		return true
+	}
+	if bot.ReplyIsToMe {
+		quoted, err := message.Quoted(bot)
+		if err == nil && quoted != nil && quoted.From != nil && quoted.From.ID == bot.Self.ID {
 			return true
 		}
 	}
@@ -372,12 +1050,21 @@ func (bot *BotAPI) IsMessageToMe(message Message) bool {
 //
 // It requires the Chattable to send.
 func (bot *BotAPI) Send(c Chattable) (Message, error) {
-	v, err := c.values()
+	return bot.SendWithContext(context.Background(), c)
+}
+
+// SendWithContext sends a Chattable item like Send, which is aborted when ctx is done.
+func (bot *BotAPI) SendWithContext(ctx context.Context, c Chattable) (Message, error) {
+	c, err := bot.prepareSend(ctx, c)
+	if err != nil {
+		return Message{}, err
+	}
+	p, err := chattableParams(c)
 	if err != nil {
 		return Message{}, err
 	}
 
-	message, err := bot.makeMessageRequest(c.method(), v)
+	message, err := bot.makeMessageRequest(ctx, c.method(), p)
 
 	if err != nil {
 		return Message{}, err
@@ -386,24 +1073,77 @@ func (bot *BotAPI) Send(c Chattable) (Message, error) {
 	return message, nil
 }
 
-func (bot *BotAPI) debugLog(context string, message ...interface{}) {
-	if bot.Debug {
-		for i, v := range message {
-			log.Printf("%s [%d]: %+v\n", context, i, v)
+// prepareSend transforms, validates and rate limits c before it is sent.
+func (bot *BotAPI) prepareSend(ctx context.Context, c Chattable) (Chattable, error) {
+	switch mc := c.(type) {
+	case MessageConfig:
+		c = bot.transformMessage(mc)
+	case PrivateMessageConfig:
+		mc.MessageConfig = bot.transformMessage(mc.MessageConfig)
+		c = mc
+	case GroupMessageConfig:
+		mc.MessageConfig = bot.transformMessage(mc.MessageConfig)
+		c = mc
+	}
+	if err := validate(c); err != nil {
+		return c, err
+	}
+	if chat, ok := messageChat(c); ok && bot.RateLimiter != nil {
+		if err := bot.RateLimiter.Wait(ctx, chat); err != nil {
+			return c, err
 		}
 	}
+	if _, ok := messageChat(c); ok && bot.ArrayMessages {
+		c = arrayMessageConfig{c}
+	}
+	return c, nil
+}
+
+// messageChat returns the chat of c if it is a message.
+func messageChat(c Chattable) (BaseChat, bool) {
+	switch mc := c.(type) {
+	case MessageConfig:
+		return mc.BaseChat, true
+	case PrivateMessageConfig:
+		return mc.BaseChat, true
+	case GroupMessageConfig:
+		return mc.BaseChat, true
+	default:
+		return BaseChat{}, false
+	}
+}
+
+// validate checks c if it implements Validator.
+func validate(c Chattable) error {
+	vc, ok := c.(Validator)
+	if !ok {
+		return nil
+	}
+	err := vc.Validate()
+	if ve, ok := err.(*ValidationError); ok && ve.Method == "" {
+		ve.Method = c.method()
+	}
+	return err
 }
 
 // Do will send a Chattable item to Coolq.
 //
 // It requires the Chattable to send.
 func (bot *BotAPI) Do(c Chattable) (APIResponse, error) {
-	v, err := c.values()
+	return bot.DoWithContext(context.Background(), c)
+}
+
+// DoWithContext sends a Chattable item like Do, which is aborted when ctx is done.
+func (bot *BotAPI) DoWithContext(ctx context.Context, c Chattable) (APIResponse, error) {
+	if err := validate(c); err != nil {
+		return APIResponse{}, err
+	}
+	p, err := chattableParams(c)
 	if err != nil {
 		return APIResponse{}, err
 	}
 
-	resp, err := bot.MakeRequest(c.method(), v)
+	resp, err := bot.MakeRequestWithParams(ctx, c.method(), p)
 
 	if err != nil {
 		return APIResponse{}, err
@@ -412,6 +1152,18 @@ func (bot *BotAPI) Do(c Chattable) (APIResponse, error) {
 	return resp, nil
 }
 
+// DoInto will send a Chattable item to Coolq, and decode the data of the response into result.
+func (bot *BotAPI) DoInto(c Chattable, result interface{}) error {
+	resp, err := bot.Do(c)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return decodeData(c.method(), resp.Data, result)
+}
+
 // ParseRawMessage parses message
 func (update *Update) ParseRawMessage() {
 	text, ok := update.RawMessage.(string)
@@ -434,6 +1186,10 @@ func (update *Update) ParseRawMessage() {
 	if chat.IsDiscuss() {
 		chat.ID = int64(update.DiscussID)
 	}
+	if chat.IsGuild() {
+		chat.GuildID = update.GuildID
+		chat.ChannelID = update.ChannelID
+	}
 	message, _ := cqcode.ParseMessage(update.RawMessage)
 	if !ok {
 		text = message.CQString()
@@ -464,6 +1220,7 @@ func (update *Update) ParseRawMessage() {
 		Chat:      &chat,
 		Text:      text,
 		SubType:   messageSubType,
+		Time:      update.Time,
 	}
 	update.Text = text
 	if update.PostType == "event" {
@@ -484,10 +1241,11 @@ func (bot *BotAPI) PreloadUserInfo(update *Update) {
 	var user User
 	var err error
 	if update.Message.Chat.Type == "group" {
-		user, err = bot.GetGroupMemberInfo(update.GroupID, update.UserID, false)
+		member, err := bot.GetGroupMemberInfo(update.GroupID, update.UserID, false)
 		if err != nil {
 			return
 		}
+		user = member.User()
 	} else {
 		user, err = bot.GetStrangerInfo(update.UserID)
 		if err != nil {
@@ -498,6 +1256,31 @@ func (bot *BotAPI) PreloadUserInfo(update *Update) {
 	update.Message.From = &user
 }
 
+// prepareUpdate parses an incoming update and fills in the information
+// according to the config.
+func (bot *BotAPI) prepareUpdate(update *Update, config BaseUpdateConfig) {
+	atomic.AddInt64(&bot.counters().updatesReceived, 1)
+	if update.PostType == "message" {
+		atomic.AddInt64(&bot.counters().messagesReceived, 1)
+	}
+	update.ParseRawMessage()
+	bot.invalidateCache(update)
+	if config.PreloadUserInfo && update.Sender == nil {
+		bot.PreloadUserInfo(update)
+	}
+	// guild messages are neither looked up nor kept, since their IDs are strings
+	if config.ResolveReply && update.Message != nil && update.GuildMessageID == "" {
+		quoted, err := update.Message.Quoted(bot)
+		if err != nil {
+			bot.debugLog("ResolveReply", "failed to resolve the message replied to", err)
+		}
+		update.Message.ReplyTo = quoted
+	}
+	if bot.MessageStore != nil && update.Message != nil && update.GuildMessageID == "" {
+		bot.MessageStore.Put(update.Message)
+	}
+}
+
 // GetUpdates fetches updates over long polling or websocket.
 // https://github.com/richardchien/cqhttp-ext-long-polling
 //
@@ -506,14 +1289,25 @@ func (bot *BotAPI) PreloadUserInfo(update *Update) {
 // Set Timeout to a large number to reduce requests so you can get updates
 // instantly instead of having to wait between requests.
 func (bot *BotAPI) GetUpdates(config UpdateConfig) ([]Update, error) {
+	return bot.GetUpdatesWithContext(context.Background(), config)
+}
+
+// GetUpdatesWithContext fetches updates like GetUpdates, and aborts the long polling
+// request when ctx is done.
+//
+// Receiving an update over websocket cannot be aborted, unless bot.WSUniversal is set,
+// but redialing a dropped connection is.
+func (bot *BotAPI) GetUpdatesWithContext(ctx context.Context, config UpdateConfig) ([]Update, error) {
 	if bot.Client != nil {
-		return bot.getUpdatesViaHTTP(config)
+		return bot.getUpdatesViaHTTP(ctx, config)
+	} else if bot.WSUniversal {
+		return bot.getUpdatesViaUniversalWebSocket(ctx, config)
 	} else {
-		return bot.getUpdatesViaWebSocket(config)
+		return bot.getUpdatesViaWebSocket(ctx, config)
 	}
 }
 
-func (bot *BotAPI) getUpdatesViaHTTP(config UpdateConfig) ([]Update, error) {
+func (bot *BotAPI) getUpdatesViaHTTP(ctx context.Context, config UpdateConfig) ([]Update, error) {
 	v := url.Values{}
 	if config.Offset != 0 {
 		v.Add("offset", strconv.Itoa(config.Offset))
@@ -525,18 +1319,17 @@ func (bot *BotAPI) getUpdatesViaHTTP(config UpdateConfig) ([]Update, error) {
 		v.Add("timeout", strconv.Itoa(config.Timeout))
 	}
 
-	resp, err := bot.MakeRequest("get_updates", v)
+	resp, err := bot.MakeRequestWithContext(ctx, "get_updates", v)
 	if err != nil {
 		return []Update{}, err
 	}
 
 	var updates []Update
-	json.Unmarshal(resp.Data, &updates)
+	if err := decodeData("get_updates", resp.Data, &updates); err != nil {
+		return []Update{}, err
+	}
 	for i := range updates {
-		updates[i].ParseRawMessage()
-		if config.PreloadUserInfo && updates[i].Sender == nil {
-			bot.PreloadUserInfo(&updates[i])
-		}
+		bot.prepareUpdate(&updates[i], config.BaseUpdateConfig)
 	}
 
 	bot.debugLog("getUpdates", v, updates)
@@ -544,36 +1337,87 @@ func (bot *BotAPI) getUpdatesViaHTTP(config UpdateConfig) ([]Update, error) {
 	return updates, nil
 }
 
-func (bot *BotAPI) getUpdatesViaWebSocket(config UpdateConfig) ([]Update, error) {
+// getUpdatesViaWebSocket receives an update from the /event/ websocket, redialing it
+// until ctx is done if the connection drops.
+func (bot *BotAPI) getUpdatesViaWebSocket(ctx context.Context, config UpdateConfig) ([]Update, error) {
 	var update Update
-	if err := websocket.JSON.Receive(bot.WSEventClient, &update); err != nil {
-		return nil, err
-	}
-	update.ParseRawMessage()
-	if config.PreloadUserInfo && update.Sender == nil {
-		bot.PreloadUserInfo(&update)
+	for {
+		conn := bot.eventConn()
+		err := websocket.JSON.Receive(conn, &update)
+		if err == nil {
+			break
+		}
+		if isWSDecodeError(err) {
+			return nil, err
+		}
+		if bot.closeContext().Err() != nil {
+			return nil, errors.New("bot closed")
+		}
+		bot.logger().Warn("WS Event connection lost, reconnecting", "error", err)
+		conn.Close()
+		conn, err = bot.redialWS(ctx, "/event/")
+		if err != nil {
+			return nil, err
+		}
+		bot.wsMux.Lock()
+		bot.WSEventClient = conn
+		bot.wsMux.Unlock()
 	}
+	bot.prepareUpdate(&update, config.BaseUpdateConfig)
 	return []Update{update}, nil
 }
 
+// getUpdatesViaUniversalWebSocket waits for an event separated from API responses by receiveAPIResponses.
+func (bot *BotAPI) getUpdatesViaUniversalWebSocket(ctx context.Context, config UpdateConfig) ([]Update, error) {
+	select {
+	case update := <-bot.wsEvents:
+		bot.prepareUpdate(&update, config.BaseUpdateConfig)
+		return []Update{update}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // GetUpdatesChan starts and returns a channel that gets updates over long polling or websocket.
 // https://github.com/richardchien/cqhttp-ext-long-polling
+//
+// The channel is closed after StopReceivingUpdates is called.
 func (bot *BotAPI) GetUpdatesChan(config UpdateConfig) (UpdatesChannel, error) {
+	return bot.GetUpdatesChanWithContext(bot.stopContext(), config)
+}
+
+// GetUpdatesChanWithContext starts and returns a channel that gets updates like GetUpdatesChan,
+// and the channel is closed when ctx is done, aborting the request in flight.
+func (bot *BotAPI) GetUpdatesChanWithContext(ctx context.Context, config UpdateConfig) (UpdatesChannel, error) {
 	ch := make(chan Update, bot.Buffer)
+	bot.trackChannel(ch)
 
 	go func() {
+		defer close(ch)
+		defer bot.untrackChannel(ch)
 		for {
-			updates, err := bot.GetUpdates(config)
+			updates, err := bot.getUpdatesSafely(ctx, config)
+			if ctx.Err() != nil {
+				return
+			}
 			if err != nil {
-				log.Println(err)
-				log.Println("Failed to get updates, retrying in 3 seconds...")
-				time.Sleep(time.Second * 3)
+				bot.logger().Warn("Failed to get updates, retrying in 3 seconds...", "error", err)
+				select {
+				case <-time.After(time.Second * 3):
+				case <-ctx.Done():
+					return
+				}
+				atomic.AddInt64(&bot.counters().reconnects, 1)
 
 				continue
 			}
 
 			for _, update := range updates {
-				ch <- update
+				select {
+				case ch <- update:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
@@ -581,12 +1425,89 @@ func (bot *BotAPI) GetUpdatesChan(config UpdateConfig) (UpdatesChannel, error) {
 	return ch, nil
 }
 
+// StopReceivingUpdates stops the channels returned by GetUpdatesChan,
+// aborting the long polling requests in flight.
+func (bot *BotAPI) StopReceivingUpdates() {
+	bot.stopMux.Lock()
+	defer bot.stopMux.Unlock()
+	if bot.stop != nil {
+		bot.stop()
+	}
+	bot.stopCtx, bot.stop = nil, nil
+}
+
+// stopContext returns the context which is canceled by StopReceivingUpdates.
+func (bot *BotAPI) stopContext() context.Context {
+	bot.stopMux.Lock()
+	defer bot.stopMux.Unlock()
+	if bot.stopCtx == nil {
+		bot.stopCtx, bot.stop = context.WithCancel(context.Background())
+	}
+	return bot.stopCtx
+}
+
+// Close stops receiving updates like StopReceivingUpdates, and closes the websocket connections,
+// stopping receiving API responses and reconnecting. The bot should not be used after Close.
+func (bot *BotAPI) Close() error {
+	bot.StopReceivingUpdates()
+	bot.closeContext()
+	bot.closeBot()
+	api, event := bot.apiConn(), bot.eventConn()
+	if api != nil {
+		api.Close()
+	}
+	if event != nil && event != api {
+		event.Close()
+	}
+	return nil
+}
+
+// closeContext returns the context which is canceled by Close.
+func (bot *BotAPI) closeContext() context.Context {
+	bot.closeOnce.Do(func() {
+		bot.closeCtx, bot.closeBot = context.WithCancel(context.Background())
+	})
+	return bot.closeCtx
+}
+
 // ListenForWebSocket registers a http handler for a websocket and returns a channel that gets updates.
 func (bot *BotAPI) ListenForWebSocket(config WebhookConfig) UpdatesChannel {
 	ch := make(chan Update, bot.Buffer)
+	bot.trackChannel(ch)
+
+	http.Handle(config.Pattern, websocketServer(config, ch, func(selfID int64) *BotAPI {
+		return bot
+	}))
+
+	return ch
+}
 
-	server := websocket.Server{
+// headerSelfID returns the account of the bot in the X-Self-ID header of a request, or 0 if it is missing.
+func headerSelfID(r *http.Request) int64 {
+	if r == nil {
+		return 0
+	}
+	selfID, _ := strconv.ParseInt(r.Header.Get("X-Self-ID"), 10, 64)
+	return selfID
+}
+
+// wsUpdateOfBot returns if an update received over a connection of bot is of its account,
+// so that a connection cannot push updates of other accounts. It is unchecked if either is unknown.
+func wsUpdateOfBot(bot *BotAPI, update Update) bool {
+	return update.SelfID == 0 || bot.Self.ID == 0 || update.SelfID == bot.Self.ID
+}
+
+// websocketServer returns a websocket server passing the updates to ch.
+//
+// botOf returns the bot of an account, or nil if it is unknown. A connection is authenticated
+// and handled by the bot of its X-Self-ID header, and updates of other accounts are dropped.
+func websocketServer(config WebhookConfig, ch chan Update, botOf func(selfID int64) *BotAPI) websocket.Server {
+	return websocket.Server{
 		Handshake: func(c *websocket.Config, r *http.Request) error {
+			bot := botOf(headerSelfID(r))
+			if bot == nil {
+				return errors.New("Unknown self id")
+			}
 			if bot.Token != "" {
 				token := r.Header.Get("Authorization")[len("Token "):]
 				if token != bot.Token {
@@ -595,8 +1516,19 @@ func (bot *BotAPI) ListenForWebSocket(config WebhookConfig) UpdatesChannel {
 			}
 			return nil
 		},
-		Handler:   func(ws *websocket.Conn) {
+		Handler: func(ws *websocket.Conn) {
+			bot := botOf(headerSelfID(ws.Request()))
+			if bot == nil {
+				ws.Close()
+				return
+			}
+			if atomic.AddInt64(&bot.counters().wsConnections, 1) > 1 {
+				atomic.AddInt64(&bot.counters().reconnects, 1)
+			}
+			defer bot.recoverPanic("ListenForWebSocket")
 			connectionClose := make(chan bool)
+			defer close(connectionClose)
+			defer ws.Close()
 
 			go func() {
 				for {
@@ -614,57 +1546,82 @@ func (bot *BotAPI) ListenForWebSocket(config WebhookConfig) UpdatesChannel {
 				var update Update
 				err := websocket.JSON.Receive(ws, &update)
 				if err != nil {
-					bot.debugLog("ListenForWebSocket", "failed to read event (%v)", err)
-					connectionClose <- true
-					ws.Close()
+					bot.debugLog("ListenForWebSocket", "failed to read event", err)
 					return
 				}
-				update.ParseRawMessage()
-				if config.PreloadUserInfo {
-					bot.PreloadUserInfo(&update)
+				if !wsUpdateOfBot(bot, update) {
+					bot.debugLog("ListenForWebSocket", "dropped update of another account", update.SelfID)
+					continue
 				}
+				bot.prepareUpdate(&update, config.BaseUpdateConfig)
 				bot.debugLog("ListenForWebSocket", update)
 				ch <- update
 			}
 		},
 	}
+}
+
+// bufferPool holds buffers for reading webhook requests.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
 
-	http.Handle(config.Pattern, server)
+// signatureHashes are the hashes of HMAC signatures supported in webhooks.
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
 
-	return ch
+// verifyWebhook checks the signature of a webhook request if bot.Secret is set,
+// and calls the verifier of the config if set.
+func (bot *BotAPI) verifyWebhook(config WebhookConfig, r *http.Request, data []byte) bool {
+	if bot.Secret != "" {
+		signature := strings.SplitN(r.Header.Get("X-Signature"), "=", 2)
+		if len(signature) != 2 {
+			bot.debugLog("ListenForWebhook HMAC", "missing signature")
+			return false
+		}
+		algorithm, expectedMac := signature[0], signature[1]
+		if config.SignatureAlgorithm != "" && algorithm != config.SignatureAlgorithm {
+			bot.debugLog("ListenForWebhook HMAC", "unexpected algorithm "+algorithm)
+			return false
+		}
+		newHash, ok := signatureHashes[algorithm]
+		if !ok {
+			bot.debugLog("ListenForWebhook HMAC", "unsupported algorithm "+algorithm)
+			return false
+		}
+		mac := hmac.New(newHash, []byte(bot.Secret))
+		mac.Write(data)
+		messageMac := hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expectedMac), []byte(messageMac)) {
+			bot.debugLog("ListenForWebhook HMAC", expectedMac, messageMac)
+			return false
+		}
+	}
+	if config.Verifier != nil {
+		if err := config.Verifier(r, data); err != nil {
+			bot.debugLog("ListenForWebhook Verifier", err)
+			return false
+		}
+	}
+	return true
 }
 
 // ListenForWebhook registers a http handler for a webhook and returns a channel that gets updates.
 func (bot *BotAPI) ListenForWebhook(config WebhookConfig) UpdatesChannel {
 	ch := make(chan Update, bot.Buffer)
+	bot.trackChannel(ch)
 
 	http.HandleFunc(config.Pattern, func(w http.ResponseWriter, r *http.Request) {
-		bytes, _ := ioutil.ReadAll(r.Body)
-
-		if bot.Secret != "" {
-			mac := hmac.New(sha1.New, []byte(bot.Secret))
-			mac.Write(bytes)
-			expectedMac := r.Header.Get("X-Signature")[len("sha1="):]
-			messageMac := hex.EncodeToString(mac.Sum(nil))
-			if expectedMac != messageMac {
-				bot.debugLog("ListenForWebhook HMAC", expectedMac, messageMac)
-				return
-			}
-		}
-
-		var update Update
-		json.Unmarshal(bytes, &update)
-
-		update.ParseRawMessage()
-		if config.PreloadUserInfo {
-			bot.PreloadUserInfo(&update)
-		}
-
-		bot.debugLog("ListenForWebhook", update)
-
-		ch <- update
-
-		w.WriteHeader(http.StatusNoContent)
+		readBody(r, func(data []byte) {
+			bot.serveWebhook(config, w, r, data, func(update Update) {
+				ch <- update
+				w.WriteHeader(http.StatusNoContent)
+			})
+		})
 	})
 
 	return ch
@@ -676,35 +1633,48 @@ func (bot *BotAPI) ListenForWebhook(config WebhookConfig) UpdatesChannel {
 func (bot *BotAPI) ListenForWebhookSync(config WebhookConfig, handler func(update Update) interface{}) {
 
 	http.HandleFunc(config.Pattern, func(w http.ResponseWriter, r *http.Request) {
-		bytes, _ := ioutil.ReadAll(r.Body)
-
-		if bot.Secret != "" {
-			mac := hmac.New(sha1.New, []byte(bot.Secret))
-			mac.Write(bytes)
-			expectedMac := r.Header.Get("X-Signature")[len("sha1="):]
-			messageMac := hex.EncodeToString(mac.Sum(nil))
-			if expectedMac != messageMac {
-				bot.debugLog("ListenForWebhook HMAC", expectedMac, messageMac)
-				return
-			}
-		}
+		readBody(r, func(data []byte) {
+			bot.serveWebhook(config, w, r, data, func(update Update) {
+				resp, _ := json.Marshal(handler(update))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write(resp)
+			})
+		})
+	})
+}
 
-		var update Update
-		json.Unmarshal(bytes, &update)
+// readBody reads the body of a request into a pooled buffer, and calls f with it.
+func readBody(r *http.Request, f func(data []byte)) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	buf.ReadFrom(r.Body)
+	f(buf.Bytes())
+}
 
-		update.ParseRawMessage()
-		if config.PreloadUserInfo {
-			bot.PreloadUserInfo(&update)
-		}
+// serveWebhook verifies a webhook request with body data, and passes the update decoded
+// to deliver, which writes the response.
+func (bot *BotAPI) serveWebhook(config WebhookConfig, w http.ResponseWriter, r *http.Request, data []byte, deliver func(update Update)) {
+	defer bot.recoverHTTPPanic("ListenForWebhook", w)
+	if !bot.verifyWebhook(config, r, data) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var update Update
+	if err := json.Unmarshal(data, &update); err != nil {
+		bot.debugLog("ListenForWebhook", "failed to decode update", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-		bot.debugLog("ListenForWebhook", update)
+	bot.prepareUpdate(&update, config.BaseUpdateConfig)
 
-		resp, _ := json.Marshal(handler(update))
+	bot.debugLog("ListenForWebhook", update)
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(resp)
-	})
+	deliver(update)
 }
 
 // SendMessage sends message to a chat.
@@ -712,11 +1682,39 @@ func (bot *BotAPI) SendMessage(chatID int64, chatType string, message interface{
 	return bot.Send(NewMessage(chatID, chatType, message))
 }
 
+// SendPrivateMessage sends message to a user with send_private_msg.
+func (bot *BotAPI) SendPrivateMessage(userID int64, message interface{}) (Message, error) {
+	return bot.Send(NewPrivateMessage(userID, message))
+}
+
+// SendGroupMessage sends message to a group with send_group_msg.
+func (bot *BotAPI) SendGroupMessage(groupID int64, message interface{}) (Message, error) {
+	return bot.Send(NewGroupMessage(groupID, message))
+}
+
+// SendGroupForwardMessage sends a merged forward message of nodes to a group.
+func (bot *BotAPI) SendGroupForwardMessage(groupID int64, nodes []ForwardNode) (Message, error) {
+	return bot.Send(NewForwardMessage(groupID, ChatTypeGroup, nodes...))
+}
+
+// SendPrivateForwardMessage sends a merged forward message of nodes to a user.
+func (bot *BotAPI) SendPrivateForwardMessage(userID int64, nodes []ForwardNode) (Message, error) {
+	return bot.Send(NewForwardMessage(userID, ChatTypePrivate, nodes...))
+}
+
 // NewMessage sends message to a chat.
 func (bot *BotAPI) NewMessage(chatID int64, chatType string) *Sender {
 	return NewSender(bot, chatID, chatType)
 }
 
+// ChatSender sends message to the chat, e.g. update.Message.Chat.
+//
+// Guild chats are not supported, whose messages fail with a *ValidationError.
+// Use SendGuildChannelMessage instead.
+func (bot *BotAPI) ChatSender(chat *Chat) *Sender {
+	return NewSender(bot, chat.ID, chat.Type)
+}
+
 // DeleteMessage deletes a message in a chat.
 func (bot *BotAPI) DeleteMessage(messageID int64) (APIResponse, error) {
 	return bot.Do(DeleteMessageConfig{
@@ -724,6 +1722,42 @@ func (bot *BotAPI) DeleteMessage(messageID int64) (APIResponse, error) {
 	})
 }
 
+// MarkMessageAsRead marks a message and the ones before it in the chat as read,
+// which is a go-cqhttp extension.
+func (bot *BotAPI) MarkMessageAsRead(messageID int64) (APIResponse, error) {
+	return bot.Do(GenericConfig{
+		Action: "mark_msg_as_read",
+		Params: map[string]interface{}{"message_id": messageID},
+	})
+}
+
+// Delete deletes the message.
+//
+// In a group, the bot needs to be an administrator to delete a message sent
+// by others, and only the owner is able to delete a message sent by an administrator.
+// If the bot lacks the permission, a *PermissionError will be returned
+// without making the request.
+func (m Message) Delete(bot *BotAPI) (APIResponse, error) {
+	if m.Chat != nil && m.Chat.IsGroup() && (m.From == nil || m.From.ID != bot.Self.ID) {
+		self, err := bot.GetGroupMemberInfo(m.Chat.ID, bot.Self.ID, false)
+		if err != nil {
+			return APIResponse{}, err
+		}
+		var role string
+		if m.From != nil {
+			role = m.From.Role
+		}
+		if self.Role != "owner" && (self.Role != "admin" || role == "owner" || role == "admin") {
+			return APIResponse{}, &PermissionError{
+				GroupID: m.Chat.ID,
+				Role:    self.Role,
+				Action:  "delete message",
+			}
+		}
+	}
+	return bot.DeleteMessage(m.MessageID)
+}
+
 // Like sends like (displayed in one's profile page) to a user.
 func (bot *BotAPI) Like(userID int64, times int) (APIResponse, error) {
 	return bot.Do(LikeConfig{
@@ -796,6 +1830,56 @@ func (bot *BotAPI) EnableAnonymousChat(groupID int64, enable bool) (APIResponse,
 	})
 }
 
+// SetGroupName renames a group, which is a go-cqhttp extension.
+func (bot *BotAPI) SetGroupName(groupID int64, name string) (APIResponse, error) {
+	return bot.Do(SetGroupNameConfig{
+		GroupID:   groupID,
+		GroupName: name,
+	})
+}
+
+// SetGroupPortrait sets the portrait of a group, which is a go-cqhttp extension.
+//
+// File could be a path, []byte or io.Reader, the same as NewFileBase64.
+func (bot *BotAPI) SetGroupPortrait(groupID int64, file interface{}, cache bool) (APIResponse, error) {
+	return bot.Do(SetGroupPortraitConfig{
+		GroupID: groupID,
+		File:    file,
+		Cache:   cache,
+	})
+}
+
+// UploadPrivateFile uploads a file to a friend as name, which is a go-cqhttp extension.
+//
+// File could be a local path on the host of CQ HTTP, a URL, or base64 data formatted by
+// NewFileBase64, while URL and base64 are not supported by every implementation.
+func (bot *BotAPI) UploadPrivateFile(userID int64, file string, name string) (APIResponse, error) {
+	return bot.Do(UploadPrivateFileConfig{
+		UserID: userID,
+		File:   file,
+		Name:   name,
+	})
+}
+
+// SendGroupNotice posts a notice (公告) in a group, which is a go-cqhttp extension.
+//
+// Image is optional, see SendGroupNoticeConfig.
+func (bot *BotAPI) SendGroupNotice(groupID int64, content string, image string) (APIResponse, error) {
+	return bot.Do(SendGroupNoticeConfig{
+		GroupID: groupID,
+		Content: content,
+		Image:   image,
+	})
+}
+
+// SendGroupSign signs in (群打卡) a group, which is a go-cqhttp extension.
+func (bot *BotAPI) SendGroupSign(groupID int64) (APIResponse, error) {
+	return bot.Do(GenericConfig{
+		Action: "send_group_sign",
+		Params: map[string]interface{}{"group_id": groupID},
+	})
+}
+
 // SetChatMemberCard sets a chat member's 群名片 in the group.
 func (bot *BotAPI) SetChatMemberCard(groupID int64, userID int64, card string) (APIResponse, error) {
 	return bot.Do(SetChatMemberCardConfig{
@@ -857,3 +1941,147 @@ func (bot *BotAPI) HandleGroupRequest(flag string, typ string, approve bool, rea
 		Reason: reason,
 	})
 }
+
+// DeleteFriend deletes a friend, which is a go-cqhttp extension.
+func (bot *BotAPI) DeleteFriend(userID int64) (APIResponse, error) {
+	return bot.Do(GenericConfig{
+		Action: "delete_friend",
+		Params: map[string]interface{}{"user_id": userID},
+	})
+}
+
+// DeleteUnidirectionalFriend deletes a user from GetUnidirectionalFriendList,
+// which is a go-cqhttp extension.
+func (bot *BotAPI) DeleteUnidirectionalFriend(userID int64) (APIResponse, error) {
+	return bot.Do(GenericConfig{
+		Action: "delete_unidirectional_friend",
+		Params: map[string]interface{}{"user_id": userID},
+	})
+}
+
+// SetQQProfile sets the profile of the bot, which is a go-cqhttp extension.
+func (bot *BotAPI) SetQQProfile(nickname string, company string, email string, college string, personalNote string) (APIResponse, error) {
+	return bot.Do(GenericConfig{
+		Action: "set_qq_profile",
+		Params: map[string]interface{}{
+			"nickname":      nickname,
+			"company":       company,
+			"email":         email,
+			"college":       college,
+			"personal_note": personalNote,
+		},
+	})
+}
+
+// SetModelShow sets the device model shown with the online status of the bot,
+// which is one of the variants from GetModelShow. It is a go-cqhttp extension.
+func (bot *BotAPI) SetModelShow(model string, modelShow string) (APIResponse, error) {
+	return bot.Do(GenericConfig{
+		Action: "_set_model_show",
+		Params: map[string]interface{}{
+			"model":      model,
+			"model_show": modelShow,
+		},
+	})
+}
+
+// GetModelShow fetches the variants of a device model which could be shown,
+// which is a go-cqhttp extension.
+func (bot *BotAPI) GetModelShow(model string) ([]ModelShow, error) {
+	v := url.Values{}
+	v.Add("model", model)
+	resp, err := bot.MakeRequest("_get_model_show", v)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Variants []ModelShow `json:"variants"`
+	}
+	if err := decodeData("_get_model_show", resp.Data, &data); err != nil {
+		return nil, err
+	}
+	if data.Variants == nil {
+		data.Variants = make([]ModelShow, 0)
+	}
+	return data.Variants, nil
+}
+
+// SetRestart restarts CQ HTTP after delay.
+//
+// The response of CQ HTTP is async, which is not an error.
+func (bot *BotAPI) SetRestart(delay time.Duration) (APIResponse, error) {
+	resp, err := bot.Do(GenericConfig{
+		Action: "set_restart",
+		Params: map[string]interface{}{"delay": delay.Milliseconds()},
+	})
+	if IsAsync(err) {
+		return resp, nil
+	}
+	return resp, err
+}
+
+// CleanCache cleans the images and records cached by CQ HTTP.
+func (bot *BotAPI) CleanCache() (APIResponse, error) {
+	return bot.Do(GenericConfig{Action: "clean_cache"})
+}
+
+// Approve approves the request of a request update, using the endpoint
+// and sub_type of the request.
+//
+// remarkOrReason is the remark of the friend for a friend request, and is ignored for a group request.
+func (update Update) Approve(bot *BotAPI, remarkOrReason string) (APIResponse, error) {
+	return update.handleRequest(bot, true, remarkOrReason)
+}
+
+// Reject rejects the request of a request update, using the endpoint
+// and sub_type of the request.
+//
+// reason is sent to the user for a group request, and is ignored for a friend request.
+func (update Update) Reject(bot *BotAPI, reason string) (APIResponse, error) {
+	return update.handleRequest(bot, false, reason)
+}
+
+func (update Update) handleRequest(bot *BotAPI, approve bool, remarkOrReason string) (APIResponse, error) {
+	if update.PostType != "request" {
+		return APIResponse{}, errors.New("not a request update")
+	}
+	switch update.RequestType {
+	case "friend":
+		if !approve {
+			remarkOrReason = ""
+		}
+		return bot.HandleFriendRequest(update.Flag, approve, remarkOrReason)
+	case "group":
+		if approve {
+			remarkOrReason = ""
+		}
+		return bot.HandleGroupRequest(update.Flag, update.SubType, approve, remarkOrReason)
+	default:
+		return APIResponse{}, errors.New("unknown request type " + update.RequestType)
+	}
+}
+
+// Approve approves the friend request.
+func (r *FriendRequest) Approve(bot *BotAPI) (APIResponse, error) {
+	return bot.HandleFriendRequest(r.Flag, true, "")
+}
+
+// ApproveWithRemark approves the friend request, and sets the remark of the friend.
+func (r *FriendRequest) ApproveWithRemark(bot *BotAPI, remark string) (APIResponse, error) {
+	return bot.HandleFriendRequest(r.Flag, true, remark)
+}
+
+// Reject rejects the friend request.
+func (r *FriendRequest) Reject(bot *BotAPI) (APIResponse, error) {
+	return bot.HandleFriendRequest(r.Flag, false, "")
+}
+
+// Approve approves the group request, i.e. lets the user join, or joins the group invited to.
+func (r *GroupRequest) Approve(bot *BotAPI) (APIResponse, error) {
+	return bot.HandleGroupRequest(r.Flag, r.SubType, true, "")
+}
+
+// Reject rejects the group request, and reason is sent to the user.
+func (r *GroupRequest) Reject(bot *BotAPI, reason string) (APIResponse, error) {
+	return bot.HandleGroupRequest(r.Flag, r.SubType, false, reason)
+}
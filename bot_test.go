@@ -0,0 +1,1165 @@
+package qqbotapi
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/catsworld/qq-bot-api/cqcode"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a server responding data to every request.
+func newTestServer(data interface{}) *httptest.Server {
+	b, _ := json.Marshal(data)
+	resp, _ := json.Marshal(map[string]interface{}{
+		"status":  "ok",
+		"retcode": 0,
+		"data":    json.RawMessage(b),
+	})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			gz.Write(resp)
+			return
+		}
+		w.Write(resp)
+	}))
+}
+
+func newMemberList(n int) []map[string]interface{} {
+	users := make([]map[string]interface{}, n)
+	for i := range users {
+		users[i] = map[string]interface{}{
+			"group_id":          10000,
+			"user_id":           100000 + i,
+			"nickname":          "nickname",
+			"card":              "card",
+			"role":              "member",
+			"join_time":         1500000000,
+			"last_sent_time":    1500000000,
+			"title_expire_time": 0,
+		}
+	}
+	return users
+}
+
+func TestGzipResponse(t *testing.T) {
+	server := newTestServer(newMemberList(10))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	users, err := bot.GetGroupMemberList(10000)
+	if err == nil && len(users) == 10 && users[9].UserID == 100009 {
+		t.Log("TestGzipResponse passed")
+	} else {
+		t.Errorf("TestGzipResponse failed: %v %v", err, users)
+	}
+}
+
+func benchmarkGetGroupMemberList(b *testing.B, disableCompression bool) {
+	server := newTestServer(newMemberList(3000))
+	defer server.Close()
+
+	bot := &BotAPI{
+		Client:             server.Client(),
+		APIEndpoint:        server.URL,
+		DisableCompression: disableCompression,
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := bot.GetGroupMemberList(10000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetGroupMemberList(b *testing.B) {
+	b.Run("gzip", func(b *testing.B) {
+		benchmarkGetGroupMemberList(b, false)
+	})
+	b.Run("identity", func(b *testing.B) {
+		benchmarkGetGroupMemberList(b, true)
+	})
+}
+
+func BenchmarkParseRawMessage(b *testing.B) {
+	data := []byte(`{"time":1515204254,"post_type":"message","message_type":"group","sub_type":"normal","message_id":12,"group_id":123456,"user_id":12345678,"message":"[CQ:at,qq=123456] 今天吃什么[CQ:face,id=14] [CQ:image,file=1.jpg,url=https://example.com/1.jpg]","raw_message":"","font":456,"sender":{"user_id":12345678,"nickname":"小不点","card":"","role":"member"}}`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var update Update
+		json.Unmarshal(data, &update)
+		update.ParseRawMessage()
+	}
+}
+
+func TestResponseHook(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"user_id": 10000, "nickname": "bot"})
+	defer server.Close()
+
+	var captured string
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	bot.ResponseHook = func(endpoint string, body []byte) {
+		captured = endpoint + " " + string(body)
+	}
+	user, err := bot.GetMe()
+	if err == nil && user.ID == 10000 && captured == `get_login_info {"data":{"nickname":"bot","user_id":10000},"retcode":0,"status":"ok"}` {
+		t.Log("TestResponseHook passed")
+	} else {
+		t.Errorf("TestResponseHook failed: %v %v", err, captured)
+	}
+}
+
+func TestStreamGroupMemberList(t *testing.T) {
+	server := newTestServer(newMemberList(100))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	var count int
+	var last int64
+	err := bot.StreamGroupMemberList(10000, func(member GroupMember) error {
+		count++
+		last = member.UserID
+		return nil
+	})
+	if err == nil && count == 100 && last == 100099 {
+		t.Log("TestStreamGroupMemberList passed")
+	} else {
+		t.Errorf("TestStreamGroupMemberList failed: %v %v %v", err, count, last)
+	}
+}
+
+func TestStreamGroupMemberList_Failed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"user_id":100000}],"retcode":100,"status":"failed"}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	var endpoints []string
+	bot.UseRequestMiddleware(func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error) {
+		endpoints = append(endpoints, endpoint)
+		return next(ctx, endpoint, params)
+	})
+	var count int
+	err := bot.StreamGroupMemberList(10000, func(member GroupMember) error {
+		count++
+		return nil
+	})
+	_, failed := err.(*APIError)
+	stats := bot.Stats()
+	if failed && count == 0 && stats.APICalls == 1 && stats.APIErrors == 1 && reflect.DeepEqual(endpoints, []string{"get_group_member_list"}) {
+		t.Log("TestStreamGroupMemberList_Failed passed")
+	} else {
+		t.Errorf("TestStreamGroupMemberList_Failed failed: %v %v %+v %v", err, count, stats, endpoints)
+	}
+}
+
+func BenchmarkStreamGroupMemberList(b *testing.B) {
+	server := newTestServer(newMemberList(3000))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := bot.StreamGroupMemberList(10000, func(member GroupMember) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetGroupMessageHistory(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"messages": []map[string]interface{}{
+			{"message_id": 1, "message_seq": 100, "message_type": "group", "sub_type": "normal", "group_id": 10000, "sender": map[string]interface{}{"user_id": 100000}, "message": "hi"},
+		},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	messages, err := bot.GetGroupMessageHistory(10000, 0)
+	if err == nil && len(messages) == 1 && messages[0].Text == "hi" && messages[0].Chat.IsGroup() && messages[0].MessageSeq == 100 && messages[0].SubType == "normal" {
+		t.Log("TestGetGroupMessageHistory passed")
+	} else {
+		t.Errorf("TestGetGroupMessageHistory failed: %v %v", err, messages)
+	}
+}
+
+func TestUpdate_Approve(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	var endpoints []string
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	bot.ResponseHook = func(endpoint string, body []byte) {
+		endpoints = append(endpoints, endpoint)
+	}
+	friend := Update{PostType: "request", RequestType: "friend", Flag: "flag"}
+	group := Update{PostType: "request", RequestType: "group", SubType: "invite", Flag: "flag"}
+	_, err1 := friend.Approve(bot, "remark")
+	_, err2 := group.Reject(bot, "reason")
+	_, err3 := Update{PostType: "message"}.Approve(bot, "")
+	if err1 == nil && err2 == nil && err3 != nil && strings.Join(endpoints, ",") == "set_friend_add_request,set_group_add_request" {
+		t.Log("TestUpdate_Approve passed")
+	} else {
+		t.Errorf("TestUpdate_Approve failed: %v %v %v %v", err1, err2, err3, endpoints)
+	}
+}
+
+func TestGroupRequest_Reject(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":null}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	update := Update{PostType: "request", RequestType: "group", SubType: "add", GroupID: 10000, Flag: "flag", Comment: "hi"}
+	_, err := update.GroupJoinRequest().Reject(bot, "reason")
+	if err == nil && form.Get("flag") == "flag" && form.Get("sub_type") == "add" && form.Get("approve") == "false" && form.Get("reason") == "reason" {
+		t.Log("TestGroupRequest_Reject passed")
+	} else {
+		t.Errorf("TestGroupRequest_Reject failed: %v %v", err, form)
+	}
+}
+
+func TestObserveErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"failed","retcode":102,"data":null}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	var failed, alerted int
+	bot.ObserveErrors(func(event APIErrorEvent) {
+		failed++
+	})
+	bot.AlertOnRetCode(102, 3, time.Minute, func(event APIErrorEvent) {
+		alerted = event.Count
+	})
+	for i := 0; i < 4; i++ {
+		bot.GetGroupList()
+	}
+	if failed == 4 && alerted == 3 {
+		t.Log("TestObserveErrors passed")
+	} else {
+		t.Errorf("TestObserveErrors failed: %v %v", failed, alerted)
+	}
+}
+
+func TestResolveReply(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"message_id":   11,
+		"message_type": "group",
+		"group_id":     10000,
+		"sender":       map[string]interface{}{"user_id": 20000},
+		"message":      "original",
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	update := Update{PostType: "message", MessageType: "group", GroupID: 10000, RawMessage: "[CQ:reply,id=11]reply"}
+	bot.prepareUpdate(&update, BaseUpdateConfig{ResolveReply: true})
+	other := Update{PostType: "message", MessageType: "group", GroupID: 10000, RawMessage: "hi"}
+	bot.prepareUpdate(&other, BaseUpdateConfig{ResolveReply: true})
+
+	replyTo := update.Message.ReplyTo
+	if replyTo != nil && replyTo.MessageID == 11 && replyTo.Text == "original" && replyTo.From.ID == 20000 && other.Message.ReplyTo == nil {
+		t.Log("TestResolveReply passed")
+	} else {
+		t.Errorf("TestResolveReply failed: %v %v", replyTo, other.Message.ReplyTo)
+	}
+}
+
+func TestStats(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"message_id": 12})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	bot.SendMessage(10000, "group", "hi")
+	bot.Do(DeleteMessageConfig{})
+	bot.DeleteMessage(12)
+	update := Update{PostType: "message", MessageType: "group", RawMessage: "hi"}
+	bot.prepareUpdate(&update, BaseUpdateConfig{})
+
+	stats := bot.Stats()
+	w := httptest.NewRecorder()
+	bot.StatsHandler().ServeHTTP(w, nil)
+	var served Stats
+	json.Unmarshal(w.Body.Bytes(), &served)
+	if stats.MessagesSent == 1 && stats.APICalls == 2 && stats.MessagesReceived == 1 && served == stats {
+		t.Log("TestStats passed")
+	} else {
+		t.Errorf("TestStats failed: %+v %+v", stats, served)
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	bot := &BotAPI{Secret: "secret"}
+	data := []byte(`{"post_type":"message"}`)
+	sign := func(algorithm string) *http.Request {
+		r := httptest.NewRequest("POST", "/", nil)
+		newHash := signatureHashes[algorithm]
+		mac := hmac.New(newHash, []byte(bot.Secret))
+		mac.Write(data)
+		r.Header.Set("X-Signature", algorithm+"="+hex.EncodeToString(mac.Sum(nil)))
+		return r
+	}
+	sha256Config := WebhookConfig{SignatureAlgorithm: "sha256"}
+	proxyConfig := WebhookConfig{Verifier: func(r *http.Request, body []byte) error {
+		if r.Header.Get("X-Proxy-Auth") != "token" {
+			return errors.New("bad proxy auth")
+		}
+		return nil
+	}}
+	results := []bool{
+		bot.verifyWebhook(WebhookConfig{}, sign("sha1"), data),
+		bot.verifyWebhook(WebhookConfig{}, sign("sha256"), data),
+		bot.verifyWebhook(sha256Config, sign("sha1"), data),
+		bot.verifyWebhook(WebhookConfig{}, httptest.NewRequest("POST", "/", nil), data),
+		bot.verifyWebhook(proxyConfig, sign("sha1"), data),
+	}
+	if results[0] && results[1] && !results[2] && !results[3] && !results[4] {
+		t.Log("TestVerifyWebhook passed")
+	} else {
+		t.Errorf("TestVerifyWebhook failed: %v", results)
+	}
+}
+
+func TestStopReceivingUpdates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	ch, _ := bot.GetUpdatesChan(UpdateConfig{Timeout: 10})
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	bot.StopReceivingUpdates()
+	_, ok := <-ch
+	if !ok && time.Since(start) < time.Second {
+		t.Log("TestStopReceivingUpdates passed")
+	} else {
+		t.Errorf("TestStopReceivingUpdates failed: %v %v", ok, time.Since(start))
+	}
+}
+
+func TestDoWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := bot.DoWithContext(ctx, DeleteMessageConfig{MessageID: 12})
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		t.Log("TestDoWithContext passed")
+	} else {
+		t.Errorf("TestDoWithContext failed: %v", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"failed","retcode":100,"msg":"NOT_MANAGEABLE","wording":"机器人权限不足","data":null}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	_, err := bot.KickChatMember(10000, 100000, false)
+	apiErr, ok := err.(*APIError)
+	if ok && apiErr.RetCode == 100 && IsPermissionDenied(err) && !IsAsync(err) && err.Error() == "failed 100: 机器人权限不足" {
+		t.Log("TestAPIError passed")
+	} else {
+		t.Errorf("TestAPIError failed: %v", err)
+	}
+}
+
+func TestAPIError_Wrapped(t *testing.T) {
+	denied := fmt.Errorf("kick: %w", &APIError{Status: "failed", RetCode: 100, Msg: "NOT_MANAGEABLE"})
+	async := fmt.Errorf("send: %w", &APIError{Status: "async", RetCode: 1})
+	permission := fmt.Errorf("kick: %w", &PermissionError{GroupID: 10000, Role: "member", Action: "kick"})
+	if IsPermissionDenied(denied) && IsPermissionDenied(permission) && IsAsync(async) && !IsAsync(denied) {
+		t.Log("TestAPIError_Wrapped passed")
+	} else {
+		t.Errorf("TestAPIError_Wrapped failed")
+	}
+}
+
+func TestNewBotAPIWithHTTPClient(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"user_id": 10000, "nickname": "bot"})
+	defer server.Close()
+
+	bot, err := NewBotAPIWithHTTPClient("", server.URL, "", server.Client())
+	if err == nil && bot.Client == server.Client() && bot.Self.ID == 10000 {
+		t.Log("TestNewBotAPIWithHTTPClient passed")
+	} else {
+		t.Errorf("TestNewBotAPIWithHTTPClient failed: %v", err)
+	}
+}
+
+func TestNewLazyBotAPI(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"user_id": 10000, "nickname": "bot"})
+	defer server.Close()
+
+	wsBot, err1 := NewLazyBotAPI("", "ws://127.0.0.1:1", "")
+	_, err2 := wsBot.GetMe()
+	bot, err3 := NewLazyBotAPI("", server.URL, "")
+	err4 := bot.Init()
+	if err1 == nil && err2 != nil && err3 == nil && err4 == nil && bot.Self.ID == 10000 {
+		t.Log("TestNewLazyBotAPI passed")
+	} else {
+		t.Errorf("TestNewLazyBotAPI failed: %v %v %v %v", err1, err2, err3, err4)
+	}
+}
+
+func TestNextWSBackoff(t *testing.T) {
+	backoff := time.Second
+	for i := 0; i < 10; i++ {
+		backoff = nextWSBackoff(backoff, 0)
+	}
+	if backoff == time.Minute && nextWSBackoff(time.Second, 3*time.Second) == 2*time.Second &&
+		nextWSBackoff(2*time.Second, 3*time.Second) == 3*time.Second {
+		t.Log("TestNextWSBackoff passed")
+	} else {
+		t.Errorf("TestNextWSBackoff failed: %v", backoff)
+	}
+}
+
+func TestGetUpdatesViaUniversalWebSocket(t *testing.T) {
+	bot := &BotAPI{WSUniversal: true, wsEvents: make(chan Update, 1)}
+	bot.wsEvents <- Update{PostType: "message", MessageType: "private", RawMessage: "hi"}
+	updates, err := bot.GetUpdates(NewUpdate(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ctxErr := bot.GetUpdatesWithContext(ctx, NewUpdate(0))
+	if err == nil && len(updates) == 1 && updates[0].Message != nil && ctxErr == context.Canceled {
+		t.Log("TestGetUpdatesViaUniversalWebSocket passed")
+	} else {
+		t.Errorf("TestGetUpdatesViaUniversalWebSocket failed: %v %v %v", err, updates, ctxErr)
+	}
+}
+
+func TestHandleWSMessage_EventsFull(t *testing.T) {
+	bot := &BotAPI{WSUniversal: true, wsEvents: make(chan Update, 1)}
+	req, ch := bot.tracker().add("get_status", nil)
+	bot.handleWSMessage(json.RawMessage(`{"post_type":"meta_event","meta_event_type":"heartbeat"}`))
+	bot.handleWSMessage(json.RawMessage(`{"post_type":"meta_event","meta_event_type":"heartbeat"}`))
+	bot.handleWSMessage(json.RawMessage(fmt.Sprintf(`{"status":"ok","retcode":0,"echo":%d}`, req.Echo)))
+
+	select {
+	case res := <-ch:
+		if res.resp.Status == "ok" && len(bot.wsEvents) == 1 {
+			t.Log("TestHandleWSMessage_EventsFull passed")
+		} else {
+			t.Errorf("TestHandleWSMessage_EventsFull failed: %+v %v", res, len(bot.wsEvents))
+		}
+	default:
+		t.Errorf("TestHandleWSMessage_EventsFull failed: response not delivered")
+	}
+}
+
+func TestClose(t *testing.T) {
+	bot := &BotAPI{APIEndpoint: "ws://127.0.0.1:1"}
+	stopCtx := bot.stopContext()
+	_, ch := bot.tracker().add("send_msg", nil)
+	bot.Close()
+
+	bot.receiveAPIResponses()
+	res := <-ch
+	_, err := bot.redialWS(bot.closeContext(), "/api/")
+	if stopCtx.Err() != nil && res.err == ErrRequestLost && err == context.Canceled {
+		t.Log("TestClose passed")
+	} else {
+		t.Errorf("TestClose failed: %v %v %v", stopCtx.Err(), res.err, err)
+	}
+}
+
+func TestUseRequestMiddleware_Concurrent(t *testing.T) {
+	bot := &BotAPI{}
+	var calls int64
+	count := func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error) {
+		atomic.AddInt64(&calls, 1)
+		return APIResponse{Status: "ok"}, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			bot.UseRequestMiddleware(count)
+		}()
+		go func() {
+			defer wg.Done()
+			bot.MakeRequest("get_status", nil)
+		}()
+	}
+	wg.Wait()
+	bot.MakeRequest("get_status", nil)
+	if atomic.LoadInt64(&calls) >= 1 {
+		t.Log("TestUseRequestMiddleware_Concurrent passed")
+	} else {
+		t.Errorf("TestUseRequestMiddleware_Concurrent failed: %v", calls)
+	}
+}
+
+func TestUseRequestMiddleware(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"user_id": 10000, "nickname": "bot"})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	var calls []string
+	bot.UseRequestMiddleware(
+		func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error) {
+			calls = append(calls, "outer "+endpoint)
+			return next(ctx, endpoint, params)
+		},
+		func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error) {
+			calls = append(calls, "inner "+endpoint)
+			if endpoint == "send_msg" {
+				return APIResponse{Status: "ok"}, nil
+			}
+			return next(ctx, endpoint, params)
+		},
+	)
+	self, err := bot.GetMe()
+	_, sendErr := bot.MakeRequest("send_msg", nil)
+	if err == nil && sendErr == nil && self.ID == 10000 && len(calls) == 4 &&
+		calls[0] == "outer get_login_info" && calls[1] == "inner get_login_info" {
+		t.Log("TestUseRequestMiddleware passed")
+	} else {
+		t.Errorf("TestUseRequestMiddleware failed: %v %v %v %v", err, sendErr, self, calls)
+	}
+}
+
+// recordLogger records the messages logged.
+type recordLogger struct {
+	msgs []string
+}
+
+func (l *recordLogger) Debug(msg string, args ...interface{}) { l.msgs = append(l.msgs, "DEBUG "+msg) }
+func (l *recordLogger) Info(msg string, args ...interface{})  { l.msgs = append(l.msgs, "INFO "+msg) }
+func (l *recordLogger) Warn(msg string, args ...interface{})  { l.msgs = append(l.msgs, "WARN "+msg) }
+func (l *recordLogger) Error(msg string, args ...interface{}) { l.msgs = append(l.msgs, "ERROR "+msg) }
+
+func TestLogger(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"user_id": 10000, "nickname": "bot"})
+	defer server.Close()
+
+	logger := &recordLogger{}
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL, Logger: logger}
+	bot.GetMe()
+	quiet := len(logger.msgs)
+	bot.Debug = true
+	bot.GetMe()
+	if quiet == 0 && len(logger.msgs) > 0 && logger.msgs[0] == "DEBUG MakeRequest" {
+		t.Log("TestLogger passed")
+	} else {
+		t.Errorf("TestLogger failed: %v", logger.msgs)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"user_id": 10000, "nickname": "bot"})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	user, err := Request[User](bot, "get_login_info", nil)
+	_, decodeErr := Request[[]Group](bot, "get_group_list", nil)
+	var de *DecodeError
+	if err == nil && user.ID == 10000 && errors.As(decodeErr, &de) && de.Endpoint == "get_group_list" {
+		t.Log("TestRequest passed")
+	} else {
+		t.Errorf("TestRequest failed: %v %v %v", err, user, decodeErr)
+	}
+}
+
+func TestParams(t *testing.T) {
+	bot := &BotAPI{}
+	var got []Params
+	bot.UseRequestMiddleware(func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error) {
+		got = append(got, params)
+		return APIResponse{Status: "ok"}, nil
+	})
+	bot.Do(GenericConfig{Action: "get_group_member_info", Params: map[string]interface{}{"group_id": int64(10000), "no_cache": true}})
+	v, _ := Params{"no_cache": true}.Values()
+	bot.MakeRequest("get_group_member_info", v)
+	if len(got) == 2 && got[0]["no_cache"] == true && got[0]["group_id"] == int64(10000) && got[1]["no_cache"] == "true" {
+		t.Log("TestParams passed")
+	} else {
+		t.Errorf("TestParams failed: %v", got)
+	}
+}
+
+func TestSendAsync(t *testing.T) {
+	bot := &BotAPI{}
+	var endpoints []string
+	bot.UseRequestMiddleware(func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error) {
+		endpoints = append(endpoints, endpoint)
+		return APIResponse{Status: "async", RetCode: 1}, checkAPIResponse(APIResponse{Status: "async", RetCode: 1})
+	})
+	err := bot.SendAsync(NewMessage(10000, "private", "hi"))
+	doErr := bot.DoAsync(DeleteMessageConfig{MessageID: 1})
+	stats := bot.Stats()
+	if err == nil && doErr == nil && len(endpoints) == 2 && endpoints[0] == "send_msg_async" &&
+		endpoints[1] == "delete_msg_async" && stats.APIErrors == 0 && stats.MessagesSent == 1 {
+		t.Log("TestSendAsync passed")
+	} else {
+		t.Errorf("TestSendAsync failed: %v %v %v %+v", err, doErr, endpoints, stats)
+	}
+}
+
+func TestGetUpdatesChan_Untracked(t *testing.T) {
+	server := newTestServer([]interface{}{})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := bot.GetUpdatesChanWithContext(ctx, NewUpdate(0))
+	cancel()
+	for range updates {
+	}
+	bot.counters().mux.Lock()
+	channels := len(bot.counters().channels)
+	bot.counters().mux.Unlock()
+	if err == nil && channels == 0 {
+		t.Log("TestGetUpdatesChan_Untracked passed")
+	} else {
+		t.Errorf("TestGetUpdatesChan_Untracked failed: %v %v", err, channels)
+	}
+}
+
+func TestEnableFailover(t *testing.T) {
+	primary := newTestServer(nil)
+	primary.Close()
+	standby := newTestServer(map[string]interface{}{"user_id": 10000, "nickname": "bot"})
+	defer standby.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bot := &BotAPI{Client: standby.Client(), APIEndpoint: primary.URL}
+	err := bot.EnableFailover(ctx, FailoverConfig{Endpoints: []string{primary.URL, standby.URL}})
+	self, getErr := bot.GetMe()
+	if err == nil && getErr == nil && self.ID == 10000 && bot.ActiveEndpoint() == standby.URL {
+		t.Log("TestEnableFailover passed")
+	} else {
+		t.Errorf("TestEnableFailover failed: %v %v %v %v", err, getErr, self, bot.ActiveEndpoint())
+	}
+}
+
+func TestEnableFailover_Again(t *testing.T) {
+	standby := newTestServer(nil)
+	defer standby.Close()
+
+	bot := &BotAPI{Client: standby.Client(), APIEndpoint: standby.URL}
+	bot.EnableFailover(context.Background(), FailoverConfig{Endpoints: []string{"http://127.0.0.1:1", standby.URL}})
+	first := bot.failoverState()
+	err := bot.EnableFailover(context.Background(), FailoverConfig{Endpoints: []string{standby.URL}})
+	defer bot.failoverState().stop()
+
+	var stopped bool
+	select {
+	case <-first.done:
+		stopped = true
+	case <-time.After(time.Second):
+	}
+	if err == nil && stopped && bot.ActiveEndpoint() == standby.URL {
+		t.Log("TestEnableFailover_Again passed")
+	} else {
+		t.Errorf("TestEnableFailover_Again failed: %v %v %v", err, stopped, bot.ActiveEndpoint())
+	}
+}
+
+func TestInfoCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"group_id":10000,"user_id":100000,"card":"card"}}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL, InfoCacheTTL: time.Minute}
+	bot.GetGroupMemberInfo(10000, 100000, false)
+	member, err := bot.GetGroupMemberInfo(10000, 100000, false)
+	cached := calls
+	bot.prepareUpdate(&Update{PostType: "notice", NoticeType: "group_card", GroupID: 10000, UserID: 100000}, BaseUpdateConfig{})
+	bot.GetGroupMemberInfo(10000, 100000, false)
+	if err == nil && member.Card == "card" && cached == 1 && calls == 2 {
+		t.Log("TestInfoCache passed")
+	} else {
+		t.Errorf("TestInfoCache failed: %v %v %v %v", err, member, cached, calls)
+	}
+}
+
+func TestPanicHandler(t *testing.T) {
+	var recovered interface{}
+	bot := &BotAPI{
+		Client:       http.DefaultClient,
+		APIEndpoint:  "http://127.0.0.1:1",
+		Logger:       &recordLogger{},
+		PanicHandler: func(r interface{}, stack []byte) { recovered = r },
+	}
+	bot.UseRequestMiddleware(func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error) {
+		panic("boom")
+	})
+	_, err := bot.getUpdatesSafely(context.Background(), NewUpdate(0))
+	var pe *PanicError
+	if errors.As(err, &pe) && recovered == "boom" {
+		t.Log("TestPanicHandler passed")
+	} else {
+		t.Errorf("TestPanicHandler failed: %v %v", err, recovered)
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"time":         1500000000,
+		"message_id":   12,
+		"message_type": "group",
+		"group_id":     10000,
+		"sender":       map[string]interface{}{"user_id": 100000, "nickname": "nickname"},
+		"message":      "hi[CQ:face,id=14]",
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	message, err := bot.GetMessage(12)
+	if err == nil && message.MessageID == 12 && message.Time == 1500000000 && message.Chat.ID == 10000 &&
+		message.From.ID == 100000 && len(*message.Message) == 2 {
+		t.Log("TestGetMessage passed")
+	} else {
+		t.Errorf("TestGetMessage failed: %v %v", err, message)
+	}
+}
+
+func TestGetForwardMessage(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"messages": []map[string]interface{}{
+			{
+				"content": "hi[CQ:face,id=14]",
+				"sender":  map[string]interface{}{"user_id": 100000, "nickname": "nickname"},
+				"time":    1500000000,
+			},
+			{
+				"message": []map[string]interface{}{
+					{"type": "forward", "data": map[string]interface{}{
+						"id": "inner",
+						"content": []map[string]interface{}{
+							{"message": []map[string]interface{}{{"type": "text", "data": map[string]interface{}{"text": "nested"}}}, "sender": map[string]interface{}{"user_id": 100001}},
+						},
+					}},
+				},
+				"sender": map[string]interface{}{"user_id": 100002, "nickname": "nickname"},
+			},
+		},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	forward, err := bot.GetForwardMessage("outer")
+	if err == nil && len(forward.Nodes) == 2 && forward.Nodes[0].UserID == 100000 && len(forward.Nodes[0].Message) == 2 &&
+		len(forward.Nodes[1].Forwards) == 1 && forward.Nodes[1].Forwards[0].ID == "inner" &&
+		len(forward.Nodes[1].Forwards[0].Nodes) == 1 && forward.Nodes[1].Forwards[0].Nodes[0].UserID == 100001 {
+		t.Log("TestGetForwardMessage passed")
+	} else {
+		t.Errorf("TestGetForwardMessage failed: %v %+v", err, forward)
+	}
+}
+
+func TestSendGroupForwardMessage(t *testing.T) {
+	var contentType string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"message_id":12}}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	message, err := bot.SendGroupForwardMessage(10000, []ForwardNode{
+		NewForwardNode("nickname", 100000, "hi"),
+		NewForwardNodeRef(11),
+	})
+	messages, _ := body["messages"].([]interface{})
+	if err == nil && message.MessageID == 12 && contentType == "application/json" && body["group_id"] == 10000.0 && len(messages) == 2 {
+		t.Log("TestSendGroupForwardMessage passed")
+	} else {
+		t.Errorf("TestSendGroupForwardMessage failed: %v %v %v %v", err, message, contentType, body)
+	}
+}
+
+func TestGetFriendList(t *testing.T) {
+	server := newTestServer([]map[string]interface{}{
+		{"user_id": 100000, "nickname": "nickname", "remark": "remark"},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	friends, err := bot.GetFriendList()
+	if err == nil && len(friends) == 1 && friends[0].ID == 100000 && friends[0].Remark == "remark" {
+		t.Log("TestGetFriendList passed")
+	} else {
+		t.Errorf("TestGetFriendList failed: %v %v", err, friends)
+	}
+}
+
+func TestGetGroupInfo(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"group_id":          10000,
+		"group_name":        "group",
+		"group_create_time": 1500000000,
+		"group_level":       2,
+		"member_count":      200,
+		"max_member_count":  200,
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	group, err := bot.GetGroupInfo(10000, true)
+	if err == nil && group.ID == 10000 && group.CreateTimeUnix == 1500000000 && group.Level == 2 && group.IsFull() {
+		t.Log("TestGetGroupInfo passed")
+	} else {
+		t.Errorf("TestGetGroupInfo failed: %v %v", err, group)
+	}
+}
+
+func TestSetGroupPortrait(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":null}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	_, err := bot.SetGroupPortrait(10000, strings.NewReader("portrait"), true)
+	if err == nil && form.Get("file") == "base64://cG9ydHJhaXQ=" && form.Get("cache") == "1" {
+		t.Log("TestSetGroupPortrait passed")
+	} else {
+		t.Errorf("TestSetGroupPortrait failed: %v %v", err, form)
+	}
+}
+
+func TestGetImage(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"file":     "/data/images/abc.jpg",
+		"size":     1024,
+		"filename": "abc.jpg",
+		"url":      "https://example.com/abc.jpg",
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	file, err := bot.GetImage("abc.image")
+	if err == nil && file.File == "/data/images/abc.jpg" && file.Size == 1024 && file.URL == "https://example.com/abc.jpg" {
+		t.Log("TestGetImage passed")
+	} else {
+		t.Errorf("TestGetImage failed: %v %v", err, file)
+	}
+}
+
+func TestCanSendImage(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"yes": true})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	yes, err := bot.CanSendImage()
+	if err == nil && yes {
+		t.Log("TestCanSendImage passed")
+	} else {
+		t.Errorf("TestCanSendImage failed: %v %v", err, yes)
+	}
+}
+
+func TestGetVersionInfo(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"app_name":         "go-cqhttp",
+		"app_version":      "v1.0.0",
+		"protocol_version": "v11",
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	version, err := bot.GetVersionInfo()
+	if err == nil && version.IsGoCQHTTP() && version.ProtocolVersion == "v11" {
+		t.Log("TestGetVersionInfo passed")
+	} else {
+		t.Errorf("TestGetVersionInfo failed: %v %v", err, version)
+	}
+}
+
+func TestSetRestart(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"status":"async","retcode":1,"data":null}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	_, err := bot.SetRestart(2 * time.Second)
+	if err == nil && form.Get("delay") == "2000" {
+		t.Log("TestSetRestart passed")
+	} else {
+		t.Errorf("TestSetRestart failed: %v %v", err, form)
+	}
+}
+
+func TestGetCredentials(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"cookies": "uin=o100000; skey=abc", "csrf_token": 12345})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	credentials, err := bot.GetCredentials("qun.qq.com")
+	if err == nil && credentials.Cookies == "uin=o100000; skey=abc" && credentials.CSRFToken == 12345 {
+		t.Log("TestGetCredentials passed")
+	} else {
+		t.Errorf("TestGetCredentials failed: %v %v", err, credentials)
+	}
+}
+
+func TestGetGroupAtAllRemain(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"can_at_all":                    true,
+		"remain_at_all_count_for_group": 9,
+		"remain_at_all_count_for_uin":   4,
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	remain, err := bot.GetGroupAtAllRemain(10000)
+	if err == nil && remain.CanAtAll && remain.ForGroup == 9 && remain.ForBot == 4 {
+		t.Log("TestGetGroupAtAllRemain passed")
+	} else {
+		t.Errorf("TestGetGroupAtAllRemain failed: %v %v", err, remain)
+	}
+}
+
+func TestGetGroupNotices(t *testing.T) {
+	server := newTestServer([]map[string]interface{}{
+		{
+			"notice_id":    "abc",
+			"sender_id":    100000,
+			"publish_time": 1500000000,
+			"message": map[string]interface{}{
+				"text":   "notice",
+				"images": []map[string]interface{}{{"id": "img", "width": "100", "height": "100"}},
+			},
+		},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	notices, err := bot.GetGroupNotices(10000)
+	if err == nil && len(notices) == 1 && notices[0].SenderID == 100000 && notices[0].Message.Text == "notice" && len(notices[0].Message.Images) == 1 {
+		t.Log("TestGetGroupNotices passed")
+	} else {
+		t.Errorf("TestGetGroupNotices failed: %v %v", err, notices)
+	}
+}
+
+func TestGetGroupSystemMsg(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"invited_requests": nil,
+		"join_requests": []map[string]interface{}{
+			{"request_id": 123, "requester_uin": 100000, "message": "hi", "group_id": 10000},
+		},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	msg, err := bot.GetGroupSystemMsg()
+	if err == nil && len(msg.JoinRequests) == 1 && msg.JoinRequests[0].Flag() == "123" && msg.JoinRequests[0].SubType == "add" {
+		t.Log("TestGetGroupSystemMsg passed")
+	} else {
+		t.Errorf("TestGetGroupSystemMsg failed: %v %v", err, msg)
+	}
+}
+
+func TestGetUnidirectionalFriendList(t *testing.T) {
+	server := newTestServer([]map[string]interface{}{
+		{"user_id": 100000, "nickname": "nickname", "source": "QQ号查找"},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	friends, err := bot.GetUnidirectionalFriendList()
+	if err == nil && len(friends) == 1 && friends[0].ID == 100000 && friends[0].Source == "QQ号查找" {
+		t.Log("TestGetUnidirectionalFriendList passed")
+	} else {
+		t.Errorf("TestGetUnidirectionalFriendList failed: %v %v", err, friends)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"file":"/data/cache/abc"}}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	file, err := bot.DownloadFile("https://example.com/a.zip", 2, map[string]string{"User-Agent": "bot", "Referer": "https://example.com"})
+	if err == nil && file == "/data/cache/abc" && form.Get("thread_count") == "2" && form.Get("headers") == "Referer=https://example.com\r\nUser-Agent=bot" {
+		t.Log("TestDownloadFile passed")
+	} else {
+		t.Errorf("TestDownloadFile failed: %v %v %v", err, file, form)
+	}
+}
+
+func TestOfflineFile_Download(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"file":"/data/cache/abc"}}`))
+	}))
+	defer server.Close()
+
+	var update Update
+	json.Unmarshal([]byte(`{"post_type":"notice","notice_type":"offline_file","user_id":10000,"file":{"name":"a.zip","size":1024,"url":"https://example.com/a.zip"}}`), &update)
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	offline := update.OfflineFile()
+	file, err := offline.Download(bot)
+	if err == nil && file == "/data/cache/abc" && offline.UserID == 10000 && offline.Name == "a.zip" && offline.Size == 1024 && form.Get("url") == "https://example.com/a.zip" {
+		t.Log("TestOfflineFile_Download passed")
+	} else {
+		t.Errorf("TestOfflineFile_Download failed: %v %v %v %v", err, file, offline, form)
+	}
+}
+
+func TestGetOnlineClients(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"clients": []map[string]interface{}{{"app_id": 537000000, "device_name": "iPhone", "device_kind": "iPhone"}},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	clients, err := bot.GetOnlineClients(true)
+	if err == nil && len(clients) == 1 && clients[0].Name == "iPhone" && clients[0].AppID == 537000000 {
+		t.Log("TestGetOnlineClients passed")
+	} else {
+		t.Errorf("TestGetOnlineClients failed: %v %v", err, clients)
+	}
+}
+
+func TestGetModelShow(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"variants": []map[string]interface{}{{"model_show": "iPhone11,2", "need_pay": false}},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	variants, err := bot.GetModelShow("iPhone")
+	if err == nil && len(variants) == 1 && variants[0].ModelShow == "iPhone11,2" && !variants[0].NeedPay {
+		t.Log("TestGetModelShow passed")
+	} else {
+		t.Errorf("TestGetModelShow failed: %v %v", err, variants)
+	}
+}
+
+func TestSendGroupMessage(t *testing.T) {
+	var path string
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		path, form = r.URL.Path, r.PostForm
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	bot.UseMessageTransformer(AppendAttribution("-- bot"))
+	message, err := bot.SendGroupMessage(10000, "hi")
+	if err == nil && message.MessageID == 1 && path == "/send_group_msg" && form.Get("message") == "hi\n-- bot" && form.Get("message_type") == "" {
+		t.Log("TestSendGroupMessage passed")
+	} else {
+		t.Errorf("TestSendGroupMessage failed: %v %v %v", err, path, form)
+	}
+}
+
+func TestArrayMessages(t *testing.T) {
+	var body struct {
+		Message []map[string]interface{} `json:"message"`
+	}
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL, ArrayMessages: true}
+	_, err := bot.SendGroupMessage(10000, "[CQ:face,id=14]hi")
+	if err == nil && contentType == "application/json" && len(body.Message) == 2 && body.Message[0]["type"] == "face" && body.Message[1]["type"] == "text" {
+		t.Log("TestArrayMessages passed")
+	} else {
+		t.Errorf("TestArrayMessages failed: %v %v %v", err, contentType, body.Message)
+	}
+}
+
+func TestIsMessageToMe(t *testing.T) {
+	bot := &BotAPI{Self: User{ID: 10000}, MessageStore: NewMemoryMessageStore(10)}
+	bot.MessageStore.Put(&Message{MessageID: 1, From: &User{ID: 10000}})
+	bot.MessageStore.Put(&Message{MessageID: 2, From: &User{ID: 20000}})
+
+	message := func(str string) Message {
+		m, _ := cqcode.ParseMessageFromString(str)
+		return Message{Message: &m}
+	}
+	before := []bool{
+		bot.IsMessageToMe(message("[CQ:at,qq=10000] hi")),
+		bot.IsMessageToMe(message("[CQ:at,qq=all] hi")),
+		bot.IsMessageToMe(message("[CQ:reply,id=1]hi")),
+	}
+	bot.MentionAllIsToMe = true
+	bot.ReplyIsToMe = true
+	after := []bool{
+		bot.IsMessageToMe(message("[CQ:at,qq=all] hi")),
+		bot.IsMessageToMe(message("[CQ:reply,id=1]hi")),
+		bot.IsMessageToMe(message("[CQ:reply,id=2]hi")),
+		bot.IsMessageToMe(message("[CQ:at,qq=20000] hi")),
+	}
+
+	if reflect.DeepEqual(before, []bool{true, false, false}) && reflect.DeepEqual(after, []bool{true, true, false, false}) {
+		t.Log("TestIsMessageToMe passed")
+	} else {
+		t.Errorf("TestIsMessageToMe failed: %v %v", before, after)
+	}
+}
@@ -0,0 +1,148 @@
+package qqbotapi
+
+import (
+	"sync"
+	"time"
+)
+
+// infoCache keeps the results of GetGroupMemberInfo, GetStrangerInfo and GetGroupList
+// for BotAPI.InfoCacheTTL.
+type infoCache struct {
+	members   map[[2]int64]cacheEntry
+	strangers map[int64]cacheEntry
+	groups    cacheEntry
+	mux       sync.Mutex
+}
+
+// cacheEntry is a cached value and when it expires.
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// valid reports whether the entry holds a value not expired.
+func (e cacheEntry) valid() bool {
+	return e.value != nil && time.Now().Before(e.expires)
+}
+
+// infoCache returns the cache of the bot, or nil if InfoCacheTTL is not set.
+func (bot *BotAPI) infoCache() *infoCache {
+	if bot.InfoCacheTTL <= 0 {
+		return nil
+	}
+	bot.cacheOnce.Do(func() {
+		bot.cache = &infoCache{
+			members:   make(map[[2]int64]cacheEntry),
+			strangers: make(map[int64]cacheEntry),
+		}
+	})
+	return bot.cache
+}
+
+// cachedMember returns the cached info of a group member.
+func (bot *BotAPI) cachedMember(groupID int64, userID int64) (GroupMember, bool) {
+	c := bot.infoCache()
+	if c == nil {
+		return GroupMember{}, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	e := c.members[[2]int64{groupID, userID}]
+	if !e.valid() {
+		return GroupMember{}, false
+	}
+	return e.value.(GroupMember), true
+}
+
+// cacheMember caches the info of a group member.
+func (bot *BotAPI) cacheMember(member GroupMember) {
+	c := bot.infoCache()
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.members[[2]int64{member.GroupID, member.UserID}] = cacheEntry{member, time.Now().Add(bot.InfoCacheTTL)}
+}
+
+// cachedStranger returns the cached info of a user.
+func (bot *BotAPI) cachedStranger(userID int64) (User, bool) {
+	c := bot.infoCache()
+	if c == nil {
+		return User{}, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	e := c.strangers[userID]
+	if !e.valid() {
+		return User{}, false
+	}
+	return e.value.(User), true
+}
+
+// cacheStranger caches the info of a user.
+func (bot *BotAPI) cacheStranger(user User) {
+	c := bot.infoCache()
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.strangers[user.ID] = cacheEntry{user, time.Now().Add(bot.InfoCacheTTL)}
+}
+
+// cachedGroups returns the cached group list.
+func (bot *BotAPI) cachedGroups() ([]Group, bool) {
+	c := bot.infoCache()
+	if c == nil {
+		return nil, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if !c.groups.valid() {
+		return nil, false
+	}
+	return append([]Group(nil), c.groups.value.([]Group)...), true
+}
+
+// cacheGroups caches the group list.
+func (bot *BotAPI) cacheGroups(groups []Group) {
+	c := bot.infoCache()
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.groups = cacheEntry{append([]Group(nil), groups...), time.Now().Add(bot.InfoCacheTTL)}
+}
+
+// invalidateCache drops the cached info changed by a notice,
+// e.g. a member joining, leaving or changing the card.
+func (bot *BotAPI) invalidateCache(update *Update) {
+	c := bot.infoCache()
+	if c == nil || update.PostType != "notice" {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	switch update.NoticeType {
+	case "group_increase", "group_decrease":
+		delete(c.members, [2]int64{update.GroupID, update.UserID})
+		c.groups = cacheEntry{}
+	case "group_card", "group_admin":
+		delete(c.members, [2]int64{update.GroupID, update.UserID})
+	}
+}
+
+// PurgeInfoCache drops all the cached info.
+func (bot *BotAPI) PurgeInfoCache() {
+	c := bot.infoCache()
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.members = make(map[[2]int64]cacheEntry)
+	c.strangers = make(map[int64]cacheEntry)
+	c.groups = cacheEntry{}
+}
@@ -0,0 +1,86 @@
+package qqbotapi
+
+import (
+	"sync"
+)
+
+// Filter returns a channel of the updates for which pred returns true.
+//
+// The returned channel is closed when ch is closed.
+func (ch UpdatesChannel) Filter(pred func(update Update) bool) UpdatesChannel {
+	out := make(chan Update, cap(ch))
+	go func() {
+		defer close(out)
+		for update := range ch {
+			if pred(update) {
+				out <- update
+			}
+		}
+	}()
+	return out
+}
+
+// Map returns a channel of the updates transformed by fn.
+//
+// The returned channel is closed when ch is closed.
+func (ch UpdatesChannel) Map(fn func(update Update) Update) UpdatesChannel {
+	out := make(chan Update, cap(ch))
+	go func() {
+		defer close(out)
+		for update := range ch {
+			out <- fn(update)
+		}
+	}()
+	return out
+}
+
+// Merge returns a channel of the updates from ch and chs, e.g. of several webhooks.
+//
+// The returned channel is closed when all of them are closed.
+func (ch UpdatesChannel) Merge(chs ...UpdatesChannel) UpdatesChannel {
+	chs = append([]UpdatesChannel{ch}, chs...)
+	out := make(chan Update, cap(ch))
+	var wg sync.WaitGroup
+	wg.Add(len(chs))
+	for _, c := range chs {
+		go func(c UpdatesChannel) {
+			defer wg.Done()
+			for update := range c {
+				out <- update
+			}
+		}(c)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee returns n channels, each of which receives every update from ch,
+// e.g. to fan out to a logger and a router.
+//
+// An update is sent to the next channel only after the previous one receives it,
+// so every channel should be consumed.
+// The returned channels are closed when ch is closed.
+func (ch UpdatesChannel) Tee(n int) []UpdatesChannel {
+	outs := make([]chan Update, n)
+	chs := make([]UpdatesChannel, n)
+	for i := range outs {
+		outs[i] = make(chan Update, cap(ch))
+		chs[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for update := range ch {
+			for _, out := range outs {
+				out <- update
+			}
+		}
+	}()
+	return chs
+}
@@ -0,0 +1,49 @@
+package qqbotapi
+
+import (
+	"sort"
+	"testing"
+)
+
+func newUpdatesChannel(ids ...int64) UpdatesChannel {
+	ch := make(chan Update, len(ids))
+	for _, id := range ids {
+		ch <- Update{PostType: "message", MessageType: "group", GroupID: id}
+	}
+	close(ch)
+	return ch
+}
+
+func TestUpdatesChannel(t *testing.T) {
+	chs := newUpdatesChannel(1, 2, 3, 4).
+		Merge(newUpdatesChannel(5, 6)).
+		Filter(func(update Update) bool {
+			return update.GroupID%2 == 0
+		}).
+		Map(func(update Update) Update {
+			update.GroupID *= 10
+			return update
+		}).
+		Tee(2)
+
+	results := make([][]int64, 2)
+	done := make(chan bool)
+	for i, ch := range chs {
+		go func(i int, ch UpdatesChannel) {
+			for update := range ch {
+				results[i] = append(results[i], update.GroupID)
+			}
+			done <- true
+		}(i, ch)
+	}
+	<-done
+	<-done
+	for _, r := range results {
+		sort.Slice(r, func(i, j int) bool { return r[i] < r[j] })
+	}
+	if len(results[0]) == 3 && results[0][0] == 20 && results[0][2] == 60 && len(results[1]) == 3 {
+		t.Log("TestUpdatesChannel passed")
+	} else {
+		t.Errorf("TestUpdatesChannel failed: %v", results)
+	}
+}
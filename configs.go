@@ -1,6 +1,8 @@
 package qqbotapi
 
 import (
+	"github.com/catsworld/qq-bot-api/cqcode"
+	"net/http"
 	"net/url"
 	"strconv"
 	"time"
@@ -12,6 +14,34 @@ type Chattable interface {
 	method() string
 }
 
+// paramsChattable is implemented by configs whose params keep their types over websocket.
+type paramsChattable interface {
+	Chattable
+	params() (Params, error)
+}
+
+// chattableParams returns the params of c, keeping their types if c implements params.
+func chattableParams(c Chattable) (Params, error) {
+	if pc, ok := c.(paramsChattable); ok {
+		return pc.params()
+	}
+	v, err := c.values()
+	return ParamsFromValues(v), err
+}
+
+// Validator is implemented by configs that can be checked before being sent,
+// so that obvious mistakes are reported locally instead of by a backend retcode.
+type Validator interface {
+	Validate() error
+}
+
+// Chat types of BaseChat.
+const (
+	ChatTypePrivate = "private"
+	ChatTypeGroup   = "group"
+	ChatTypeDiscuss = "discuss"
+)
+
 // BaseChat is base type for all chat config types.
 type BaseChat struct {
 	ChatID   int64 // required
@@ -34,6 +64,21 @@ func (chat *BaseChat) values() (url.Values, error) {
 	return v, nil
 }
 
+// Validate checks the chat ID and chat type.
+func (chat BaseChat) Validate() error {
+	switch chat.ChatType {
+	case ChatTypePrivate, ChatTypeGroup, ChatTypeDiscuss:
+	case ChatTypeGuild:
+		return &ValidationError{Field: "ChatType", Reason: "guild channels are sent to by SendGuildChannelMessage"}
+	default:
+		return &ValidationError{Field: "ChatType", Reason: "unknown chat type " + strconv.Quote(chat.ChatType)}
+	}
+	if chat.ChatID == 0 {
+		return &ValidationError{Field: "ChatID", Reason: "required"}
+	}
+	return nil
+}
+
 // MessageConfig contains information about a SendMessage request.
 type MessageConfig struct {
 	BaseChat
@@ -59,6 +104,79 @@ func (config MessageConfig) method() string {
 	return "send_msg"
 }
 
+// PrivateMessageConfig contains a message sent to a user with send_private_msg,
+// instead of send_msg as MessageConfig.
+type PrivateMessageConfig struct {
+	MessageConfig
+	// GroupID is the group to start a temporary session from, if the user is not a friend.
+	GroupID int64
+}
+
+// values returns a url.Values representation of PrivateMessageConfig.
+func (config PrivateMessageConfig) values() (url.Values, error) {
+	v, err := config.MessageConfig.values()
+	if err != nil {
+		return v, err
+	}
+
+	v.Del("message_type")
+	if config.GroupID != 0 {
+		v.Add("group_id", strconv.FormatInt(config.GroupID, 10))
+	}
+
+	return v, nil
+}
+
+// method returns CQ HTTP API method name for sending private message.
+func (config PrivateMessageConfig) method() string {
+	return "send_private_msg"
+}
+
+// Validate checks the chat, which must be a private chat.
+func (config PrivateMessageConfig) Validate() error {
+	if err := config.BaseChat.Validate(); err != nil {
+		return err
+	}
+	if config.ChatType != ChatTypePrivate {
+		return &ValidationError{Field: "ChatType", Reason: "must be private"}
+	}
+	return nil
+}
+
+// GroupMessageConfig contains a message sent to a group with send_group_msg,
+// instead of send_msg as MessageConfig.
+type GroupMessageConfig struct {
+	MessageConfig
+}
+
+// values returns a url.Values representation of GroupMessageConfig.
+func (config GroupMessageConfig) values() (url.Values, error) {
+	v, err := config.MessageConfig.values()
+	if err != nil {
+		return v, err
+	}
+
+	v.Del("message_type")
+
+	return v, nil
+}
+
+// method returns CQ HTTP API method name for sending group message.
+func (config GroupMessageConfig) method() string {
+	return "send_group_msg"
+}
+
+// Validate checks the chat, which must be a group.
+func (config GroupMessageConfig) Validate() error {
+	if err := config.BaseChat.Validate(); err != nil {
+		return err
+	}
+	if config.ChatType != ChatTypeGroup {
+		return &ValidationError{Field: "ChatType", Reason: "must be group"}
+	}
+	return nil
+}
+
 // DeleteMessageConfig contains information of a message in a chat to delete.
 type DeleteMessageConfig struct {
 	MessageID int64
@@ -69,6 +187,14 @@ func (config DeleteMessageConfig) method() string {
 	return "delete_msg"
 }
 
+// Validate checks the message ID.
+func (config DeleteMessageConfig) Validate() error {
+	if config.MessageID == 0 {
+		return &ValidationError{Field: "MessageID", Reason: "required"}
+	}
+	return nil
+}
+
 // values returns url.Values representation of DeleteMessageConfig.
 func (config DeleteMessageConfig) values() (url.Values, error) {
 	v := url.Values{}
@@ -89,6 +215,17 @@ func (config LikeConfig) method() string {
 	return "send_like"
 }
 
+// Validate checks the user ID and times, which must be 1 to 10.
+func (config LikeConfig) Validate() error {
+	if config.UserID == 0 {
+		return &ValidationError{Field: "UserID", Reason: "required"}
+	}
+	if config.Times < 1 || config.Times > 10 {
+		return &ValidationError{Field: "Times", Reason: "must be 1 to 10, got " + strconv.Itoa(config.Times)}
+	}
+	return nil
+}
+
 // values returns url.Values representation of LikeConfig.
 func (config LikeConfig) values() (url.Values, error) {
 	v := url.Values{}
@@ -112,12 +249,27 @@ func (config ChatMemberConfig) values() (url.Values, error) {
 	v := url.Values{}
 
 	v.Add("group_id", strconv.FormatInt(config.GroupID, 10))
-	v.Add("user_id", strconv.FormatInt(config.UserID, 10))
-	v.Add("flag", config.AnonymousFlag)
+	if config.AnonymousFlag != "" {
+		v.Add("flag", config.AnonymousFlag)
+	} else {
+		v.Add("user_id", strconv.FormatInt(config.UserID, 10))
+	}
 
 	return v, nil
 }
 
+// Validate checks the group ID and user ID, the user ID is not required
+// if the member is anonymous.
+func (config ChatMemberConfig) Validate() error {
+	if config.GroupID == 0 {
+		return &ValidationError{Field: "GroupID", Reason: "required"}
+	}
+	if config.UserID == 0 && config.AnonymousFlag == "" {
+		return &ValidationError{Field: "UserID", Reason: "required"}
+	}
+	return nil
+}
+
 // KickChatMemberConfig contains extra fields to kick user.
 type KickChatMemberConfig struct {
 	ChatMemberConfig
@@ -155,6 +307,17 @@ func (config RestrictChatMemberConfig) method() string {
 	return "set_group_ban"
 }
 
+// Validate checks the member and duration, 0 means to unban.
+func (config RestrictChatMemberConfig) Validate() error {
+	if err := config.ChatMemberConfig.Validate(); err != nil {
+		return err
+	}
+	if config.Duration < 0 {
+		return &ValidationError{Field: "Duration", Reason: "must not be negative"}
+	}
+	return nil
+}
+
 // values returns url.Values representation of RestrictChatMemberConfig.
 func (config RestrictChatMemberConfig) values() (url.Values, error) {
 	v, err := config.ChatMemberConfig.values()
@@ -222,7 +385,7 @@ type SetChatMemberTitleConfig struct {
 
 // method returns CQ HTTP API method name for setting title.
 func (config SetChatMemberTitleConfig) method() string {
-	return "set_group_card"
+	return "set_group_special_title"
 }
 
 // values returns url.Values representation of SetChatMemberTitleConfig.
@@ -254,6 +417,14 @@ func (config GroupControlConfig) values() (url.Values, error) {
 	return v, nil
 }
 
+// Validate checks the group ID.
+func (config GroupControlConfig) Validate() error {
+	if config.GroupID == 0 {
+		return &ValidationError{Field: "GroupID", Reason: "required"}
+	}
+	return nil
+}
+
 // RestrictAllChatMembersConfig contains fields to restrict all chat members.
 type RestrictAllChatMembersConfig struct {
 	GroupControlConfig
@@ -274,6 +445,198 @@ func (config EnableAnonymousChatConfig) method() string {
 	return "set_group_anonymous"
 }
 
+// SetGroupNameConfig contains fields to set the name of a group.
+type SetGroupNameConfig struct {
+	GroupID   int64
+	GroupName string
+}
+
+// method returns CQ HTTP API method name for setting group name.
+func (config SetGroupNameConfig) method() string {
+	return "set_group_name"
+}
+
+// values returns url.Values representation of SetGroupNameConfig.
+func (config SetGroupNameConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	v.Add("group_id", strconv.FormatInt(config.GroupID, 10))
+	v.Add("group_name", config.GroupName)
+
+	return v, nil
+}
+
+// Validate checks the group ID and the name.
+func (config SetGroupNameConfig) Validate() error {
+	if config.GroupID == 0 {
+		return &ValidationError{Field: "GroupID", Reason: "required"}
+	}
+	if config.GroupName == "" {
+		return &ValidationError{Field: "GroupName", Reason: "required"}
+	}
+	return nil
+}
+
+// SetGroupPortraitConfig contains fields to set the portrait of a group.
+//
+// File could be a path, []byte or io.Reader, as accepted by cqcode.NewFileBase64.
+type SetGroupPortraitConfig struct {
+	GroupID int64
+	File    interface{}
+	Cache   bool
+}
+
+// method returns CQ HTTP API method name for setting group portrait.
+func (config SetGroupPortraitConfig) method() string {
+	return "set_group_portrait"
+}
+
+// values returns url.Values representation of SetGroupPortraitConfig.
+func (config SetGroupPortraitConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	file, err := cqcode.NewFileBase64(config.File)
+	if err != nil {
+		return v, err
+	}
+	v.Add("group_id", strconv.FormatInt(config.GroupID, 10))
+	v.Add("file", file)
+	if config.Cache {
+		v.Add("cache", "1")
+	} else {
+		v.Add("cache", "0")
+	}
+
+	return v, nil
+}
+
+// Validate checks the group ID and the file.
+func (config SetGroupPortraitConfig) Validate() error {
+	if config.GroupID == 0 {
+		return &ValidationError{Field: "GroupID", Reason: "required"}
+	}
+	if config.File == nil {
+		return &ValidationError{Field: "File", Reason: "required"}
+	}
+	return nil
+}
+
+// UploadPrivateFileConfig contains fields to upload a file to a friend.
+//
+// File could be a local path on the host of CQ HTTP, a URL, or base64 data
+// formatted by cqcode.NewFileBase64, depending on the implementation.
+type UploadPrivateFileConfig struct {
+	UserID int64
+	File   string
+	Name   string
+}
+
+// method returns CQ HTTP API method name for uploading private file.
+func (config UploadPrivateFileConfig) method() string {
+	return "upload_private_file"
+}
+
+// values returns url.Values representation of UploadPrivateFileConfig.
+func (config UploadPrivateFileConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	v.Add("user_id", strconv.FormatInt(config.UserID, 10))
+	v.Add("file", config.File)
+	v.Add("name", config.Name)
+
+	return v, nil
+}
+
+// Validate checks the user ID, the file and the name.
+func (config UploadPrivateFileConfig) Validate() error {
+	if config.UserID == 0 {
+		return &ValidationError{Field: "UserID", Reason: "required"}
+	}
+	if config.File == "" {
+		return &ValidationError{Field: "File", Reason: "required"}
+	}
+	if config.Name == "" {
+		return &ValidationError{Field: "Name", Reason: "required"}
+	}
+	return nil
+}
+
+// SendGroupNoticeConfig contains fields to post a notice (公告) in a group.
+type SendGroupNoticeConfig struct {
+	GroupID int64
+	Content string
+	// Image is optional, which could be a local path on the host of CQ HTTP, a URL,
+	// or base64 data formatted by cqcode.NewFileBase64.
+	Image string
+}
+
+// method returns CQ HTTP API method name for sending group notice.
+func (config SendGroupNoticeConfig) method() string {
+	return "_send_group_notice"
+}
+
+// values returns url.Values representation of SendGroupNoticeConfig.
+func (config SendGroupNoticeConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	v.Add("group_id", strconv.FormatInt(config.GroupID, 10))
+	v.Add("content", config.Content)
+	if config.Image != "" {
+		v.Add("image", config.Image)
+	}
+
+	return v, nil
+}
+
+// Validate checks the group ID and the content.
+func (config SendGroupNoticeConfig) Validate() error {
+	if config.GroupID == 0 {
+		return &ValidationError{Field: "GroupID", Reason: "required"}
+	}
+	if config.Content == "" {
+		return &ValidationError{Field: "Content", Reason: "required"}
+	}
+	return nil
+}
+
+// GuildChannelMessageConfig contains a message sent to a channel of a guild (频道),
+// which is a go-cqhttp extension.
+type GuildChannelMessageConfig struct {
+	GuildID   string
+	ChannelID string
+	Text      string
+}
+
+// method returns CQ HTTP API method name for sending guild channel message.
+func (config GuildChannelMessageConfig) method() string {
+	return "send_guild_channel_msg"
+}
+
+// values returns url.Values representation of GuildChannelMessageConfig.
+func (config GuildChannelMessageConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	v.Add("guild_id", config.GuildID)
+	v.Add("channel_id", config.ChannelID)
+	v.Add("message", config.Text)
+
+	return v, nil
+}
+
+// Validate checks the guild ID, the channel ID and the text.
+func (config GuildChannelMessageConfig) Validate() error {
+	if config.GuildID == "" {
+		return &ValidationError{Field: "GuildID", Reason: "required"}
+	}
+	if config.ChannelID == "" {
+		return &ValidationError{Field: "ChannelID", Reason: "required"}
+	}
+	if config.Text == "" {
+		return &ValidationError{Field: "Text", Reason: "required"}
+	}
+	return nil
+}
+
 // LeaveChatConfig contains fields to leave a chat.
 type LeaveChatConfig struct {
 	BaseChat
@@ -290,6 +653,17 @@ func (config LeaveChatConfig) method() string {
 	}
 }
 
+// Validate checks the chat, which must be a group or a discuss.
+func (config LeaveChatConfig) Validate() error {
+	if err := config.BaseChat.Validate(); err != nil {
+		return err
+	}
+	if config.ChatType == ChatTypePrivate {
+		return &ValidationError{Field: "ChatType", Reason: "cannot leave a private chat"}
+	}
+	return nil
+}
+
 // values returns url.Values representation of LeaveChatConfig.
 func (config LeaveChatConfig) values() (url.Values, error) {
 	v, err := config.BaseChat.values()
@@ -297,7 +671,10 @@ func (config LeaveChatConfig) values() (url.Values, error) {
 		return v, err
 	}
 
-	v.Add("is_dismiss", strconv.FormatBool(config.IsDismiss))
+	v.Del("message_type")
+	if config.ChatType != "discuss" {
+		v.Add("is_dismiss", strconv.FormatBool(config.IsDismiss))
+	}
 
 	return v, nil
 }
@@ -318,6 +695,14 @@ func (config HandleRequestConfig) values() (url.Values, error) {
 	return v, nil
 }
 
+// Validate checks the request flag.
+func (config HandleRequestConfig) Validate() error {
+	if config.RequestFlag == "" {
+		return &ValidationError{Field: "RequestFlag", Reason: "required"}
+	}
+	return nil
+}
+
 // HandleFriendRequestConfig contains fields to handle a friend request.
 type HandleFriendRequestConfig struct {
 	HandleRequestConfig
@@ -353,6 +738,17 @@ func (config HandleGroupRequestConfig) method() string {
 	return "set_group_add_request"
 }
 
+// Validate checks the request flag and type, which must be "add" or "invite".
+func (config HandleGroupRequestConfig) Validate() error {
+	if err := config.HandleRequestConfig.Validate(); err != nil {
+		return err
+	}
+	if config.Type != "add" && config.Type != "invite" {
+		return &ValidationError{Field: "Type", Reason: "unknown request type " + strconv.Quote(config.Type)}
+	}
+	return nil
+}
+
 // values returns url.Values representation of HandleGroupRequestConfig.
 func (config HandleGroupRequestConfig) values() (url.Values, error) {
 	v, err := config.HandleRequestConfig.values()
@@ -360,12 +756,96 @@ func (config HandleGroupRequestConfig) values() (url.Values, error) {
 		return v, err
 	}
 
-	v.Add("type", config.Type)
+	v.Add("sub_type", config.Type)
+	v.Add("type", config.Type) // older CQ HTTP API versions only accept type
 	v.Add("reason", config.Reason)
 
 	return v, nil
 }
 
+// ForwardMessageConfig contains a merged forward message sent to a group or a user.
+//
+// It is sent in a JSON body over HTTP, since the nodes are nested.
+type ForwardMessageConfig struct {
+	BaseChat
+	Nodes []ForwardNode
+}
+
+// method returns CQ HTTP API method name for sending forward message.
+func (config ForwardMessageConfig) method() string {
+	if config.ChatType == ChatTypePrivate {
+		return "send_private_forward_msg"
+	}
+	return "send_group_forward_msg"
+}
+
+// params returns Params of ForwardMessageConfig.
+func (config ForwardMessageConfig) params() (Params, error) {
+	p := Params{}
+	if config.ChatType == ChatTypePrivate {
+		p["user_id"] = config.ChatID
+	} else {
+		p["group_id"] = config.ChatID
+	}
+	messages := make([]map[string]interface{}, 0, len(config.Nodes))
+	for _, node := range config.Nodes {
+		messages = append(messages, node.segment())
+	}
+	p["messages"] = messages
+	return p, nil
+}
+
+// values returns url.Values representation of ForwardMessageConfig.
+func (config ForwardMessageConfig) values() (url.Values, error) {
+	p, _ := config.params()
+	return p.Values()
+}
+
+// Validate checks the chat and the nodes.
+func (config ForwardMessageConfig) Validate() error {
+	if config.ChatType != ChatTypePrivate && config.ChatType != ChatTypeGroup {
+		return &ValidationError{Field: "ChatType", Reason: "only private and group are supported"}
+	}
+	if config.ChatID == 0 {
+		return &ValidationError{Field: "ChatID", Reason: "required"}
+	}
+	if len(config.Nodes) == 0 {
+		return &ValidationError{Field: "Nodes", Reason: "required"}
+	}
+	return nil
+}
+
+// GenericConfig contains an action not wrapped by this package and its params.
+//
+// Params are sent as they are, see Params.
+type GenericConfig struct {
+	Action string
+	Params map[string]interface{}
+}
+
+// method returns the action of GenericConfig.
+func (config GenericConfig) method() string {
+	return config.Action
+}
+
+// values returns url.Values representation of GenericConfig.
+func (config GenericConfig) values() (url.Values, error) {
+	return Params(config.Params).Values()
+}
+
+// params returns Params of GenericConfig.
+func (config GenericConfig) params() (Params, error) {
+	return config.Params, nil
+}
+
+// Validate checks the action.
+func (config GenericConfig) Validate() error {
+	if config.Action == "" {
+		return &ValidationError{Field: "Action", Reason: "required"}
+	}
+	return nil
+}
+
 // UpdateConfig contains information about a GetUpdates request.
 type UpdateConfig struct {
 	BaseUpdateConfig
@@ -378,9 +858,17 @@ type UpdateConfig struct {
 type WebhookConfig struct {
 	BaseUpdateConfig
 	Pattern string // the webhook endpoint
+	// SignatureAlgorithm is the required HMAC algorithm of X-Signature, "sha1" or "sha256".
+	// Both are accepted if it is empty.
+	SignatureAlgorithm string
+	// Verifier, if set, is called with every webhook request and its body
+	// after the signature is verified, and the request is rejected if it returns an error,
+	// e.g. to check auth headers added by a reverse proxy.
+	Verifier func(r *http.Request, body []byte) error
 }
 
 // BaseUpdateConfig contains information about loading updates.
 type BaseUpdateConfig struct {
 	PreloadUserInfo bool // if this is enabled, more information will be provided in Update.From
+	ResolveReply    bool // if this is enabled, the message replied to will be provided in Update.Message.ReplyTo
 }
@@ -0,0 +1,54 @@
+package qqbotapi
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	bot := &BotAPI{}
+	errs := make([]string, 0)
+	for _, c := range []Chattable{
+		LikeConfig{UserID: 10000, Times: 11},
+		NewMessage(10000, "channel", "hi"),
+		RestrictChatMemberConfig{ChatMemberConfig: ChatMemberConfig{GroupID: 10000, UserID: 10000}, Duration: -1},
+		KickChatMemberConfig{ChatMemberConfig: ChatMemberConfig{GroupID: 10000}},
+	} {
+		_, err := bot.Do(c)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) == 4 &&
+		errs[0] == "invalid Times for send_like: must be 1 to 10, got 11" &&
+		errs[1] == `invalid ChatType for send_msg: unknown chat type "channel"` &&
+		errs[2] == "invalid Duration for set_group_ban: must not be negative" &&
+		errs[3] == "invalid UserID for set_group_kick: required" {
+		t.Log("TestValidate passed")
+	} else {
+		t.Errorf("TestValidate failed: %v", errs)
+	}
+}
+
+func TestDoInto(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"message_id": 12})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	config := GenericConfig{
+		Action: "send_group_forward_msg",
+		Params: map[string]interface{}{
+			"group_id": int64(10000),
+			"messages": []map[string]interface{}{{"type": "node", "data": map[string]interface{}{"id": 1}}},
+		},
+	}
+	v, _ := config.values()
+	var result struct {
+		MessageID int64 `json:"message_id"`
+	}
+	err := bot.DoInto(config, &result)
+	if err == nil && result.MessageID == 12 && v.Get("group_id") == "10000" && v.Get("messages") == `[{"data":{"id":1},"type":"node"}]` {
+		t.Log("TestDoInto passed")
+	} else {
+		t.Errorf("TestDoInto failed: %v %v %v", err, result, v)
+	}
+}
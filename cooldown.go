@@ -0,0 +1,146 @@
+package qqbotapi
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CooldownStore keeps the cooldowns of Throttle, e.g. in Redis to share them between processes.
+type CooldownStore interface {
+	// Acquire starts the cooldown of key for d and returns true if it is not cooling down,
+	// or else returns false with the remaining duration of the cooldown.
+	Acquire(key string, d time.Duration) (ok bool, remaining time.Duration, err error)
+}
+
+// MemoryCooldownStore is a CooldownStore in memory.
+type MemoryCooldownStore struct {
+	deadlines map[string]time.Time
+	mux       sync.Mutex
+}
+
+// NewMemoryCooldownStore creates a MemoryCooldownStore.
+func NewMemoryCooldownStore() *MemoryCooldownStore {
+	return &MemoryCooldownStore{
+		deadlines: make(map[string]time.Time),
+	}
+}
+
+// Acquire starts the cooldown of key for d and returns true if it is not cooling down,
+// or else returns false with the remaining duration of the cooldown.
+func (s *MemoryCooldownStore) Acquire(key string, d time.Duration) (bool, time.Duration, error) {
+	now := timeNow()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if deadline, ok := s.deadlines[key]; ok && now.Before(deadline) {
+		return false, deadline.Sub(now), nil
+	}
+	for k, deadline := range s.deadlines {
+		if !now.Before(deadline) {
+			delete(s.deadlines, k)
+		}
+	}
+	s.deadlines[key] = now.Add(d)
+	return true, 0, nil
+}
+
+// CooldownByUser is the default key of Throttle, cooling down every user separately.
+func CooldownByUser(update Update) string {
+	if update.UserID == 0 {
+		return ""
+	}
+	return "user:" + strconv.FormatInt(update.UserID, 10)
+}
+
+// CooldownByGroup is a key of Throttle cooling down every group separately,
+// shared by its members. Updates outside groups are not throttled.
+func CooldownByGroup(update Update) string {
+	if update.GroupID == 0 {
+		return ""
+	}
+	return "group:" + strconv.FormatInt(update.GroupID, 10)
+}
+
+// CooldownByUserInGroup is a key of Throttle cooling down every user in every group separately.
+func CooldownByUserInGroup(update Update) string {
+	if update.UserID == 0 {
+		return ""
+	}
+	return "group:" + strconv.FormatInt(update.GroupID, 10) + ":user:" + strconv.FormatInt(update.UserID, 10)
+}
+
+// Throttle limits how often updates are handled, e.g. a command to once a minute for every user.
+//
+//	throttle := &qqbotapi.Throttle{
+//		Name:     "roll",
+//		Duration: time.Minute,
+//		OnThrottled: func(update qqbotapi.Update, remaining time.Duration) {
+//			bot.SendMessage(update.Message.Chat.ID, update.Message.Chat.Type, "Try again in "+remaining.String())
+//		},
+//	}
+//	ev.On("message")(throttle.Wrap(Roll))
+type Throttle struct {
+	// Name separates the cooldowns of throttles sharing a Store, e.g. the command throttled.
+	Name     string
+	Duration time.Duration
+	// Key returns the key of the cooldown of an update, or "" not to throttle it.
+	// It is CooldownByUser if not set.
+	Key func(update Update) string
+	// Store keeps the cooldowns, which are kept in memory if it is not set.
+	Store CooldownStore
+	// OnThrottled, if set, is called with the updates throttled, e.g. to reply.
+	OnThrottled func(update Update, remaining time.Duration)
+
+	storeOnce sync.Once
+}
+
+// Allow starts the cooldown of an update and returns true if it is not cooling down,
+// or else returns false with the remaining duration of the cooldown.
+func (t *Throttle) Allow(update Update) (bool, time.Duration, error) {
+	key := t.key(update)
+	if key == "" {
+		return true, 0, nil
+	}
+	return t.store().Acquire(t.Name+":"+key, t.Duration)
+}
+
+// Wrap returns a handler calling handler with the updates not throttled.
+// Updates failing to acquire the cooldown are handled.
+func (t *Throttle) Wrap(handler func(update Update)) func(update Update) {
+	return func(update Update) {
+		ok, remaining, err := t.Allow(update)
+		if err == nil && !ok {
+			if t.OnThrottled != nil {
+				t.OnThrottled(update, remaining)
+			}
+			return
+		}
+		handler(update)
+	}
+}
+
+// Middleware returns a middleware of Ev passing the updates not throttled.
+func (t *Throttle) Middleware() EvMiddleware {
+	return func(c *EvContext, next func(c *EvContext)) {
+		t.Wrap(func(update Update) {
+			next(c)
+		})(c.Update)
+	}
+}
+
+func (t *Throttle) key(update Update) string {
+	if t.Key != nil {
+		return t.Key(update)
+	}
+	return CooldownByUser(update)
+}
+
+// store returns t.Store, which is created in memory if it is nil.
+func (t *Throttle) store() CooldownStore {
+	t.storeOnce.Do(func() {
+		if t.Store == nil {
+			t.Store = NewMemoryCooldownStore()
+		}
+	})
+	return t.Store
+}
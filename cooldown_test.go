@@ -0,0 +1,45 @@
+package qqbotapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	now := time.Unix(0, 0)
+	timeNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	var handled []int64
+	var throttled []time.Duration
+	throttle := &Throttle{
+		Name:     "roll",
+		Duration: time.Minute,
+		Key:      CooldownByGroup,
+		OnThrottled: func(update Update, remaining time.Duration) {
+			throttled = append(throttled, remaining)
+		},
+	}
+	handler := throttle.Wrap(func(update Update) {
+		handled = append(handled, update.UserID)
+	})
+
+	handler(Update{GroupID: 1, UserID: 10000})
+	now = now.Add(20 * time.Second)
+	handler(Update{GroupID: 1, UserID: 20000})
+	handler(Update{GroupID: 2, UserID: 20000})
+	handler(Update{UserID: 30000})
+	now = now.Add(40 * time.Second)
+	handler(Update{GroupID: 1, UserID: 20000})
+
+	if reflect.DeepEqual(handled, []int64{10000, 20000, 30000, 20000}) && reflect.DeepEqual(throttled, []time.Duration{40 * time.Second}) {
+		t.Log("TestThrottle passed")
+	} else {
+		t.Errorf("TestThrottle failed: %v %v", handled, throttled)
+	}
+}
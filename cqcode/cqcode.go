@@ -3,13 +3,16 @@
 package cqcode
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // StrictCommand indicates that whether a command must start with a specified command prefix, default to "/".
@@ -20,6 +23,25 @@ var StrictCommand = false
 // See function #Command
 var CommandPrefix = "/"
 
+// Errors returned by ParseCQCode and ParseMedia.
+var (
+	// ErrInvalidCQCode is returned when the string is not a CQ code, e.g. the function name
+	// is missing, or it contains an unescaped "[".
+	ErrInvalidCQCode = errors.New("invalid cqcode")
+	// ErrWrongMediaType is returned when the function name does not match the media.
+	ErrWrongMediaType = errors.New("wrong media type")
+)
+
+var commandRegexp = regexp.MustCompile(`'[\s\S]*?'|"[\s\S]*?"|\S*\[CQ:[\s\S]*?\]\S*|\S+`)
+
+// segmentsPool holds buffers of MessageSegment used when parsing messages from string.
+var segmentsPool = sync.Pool{
+	New: func() interface{} {
+		segs := make([]MessageSegment, 0, 16)
+		return &segs
+	},
+}
+
 // A Message is a sort of Media.
 type Message []Media
 
@@ -121,47 +143,112 @@ func ParseMessageFromArray(msg interface{}) (Message, error) {
 // ParseMessageSegmentsFromString parses msg as type string to a sort of MessageSegment.
 // msg is the value of key "message" of the data umarshalled from the
 // API response JSON.
+//
+// It never fails, and the error is always nil: any input is parsed, without panicking,
+// into text segments and segments of CQ codes as described in ParseCQCode.
+// A CQ code which is invalid, e.g. "[CQ:]" or a nested one, is kept as text,
+// and an unclosed one is text, so that no content of the input is lost.
 func ParseMessageSegmentsFromString(str string) ([]MessageSegment, error) {
-	segs := make([]MessageSegment, 0)
-	res := regexp.MustCompile(`\[CQ:[\s\S]*?\]`).FindAllStringSubmatchIndex(str, -1)
-	i := 0
-	for _, cqc := range res {
-		if cqc[0] > i {
-			// There is a text message before this cqc
-			seg := MessageSegment{
-				Type: "text",
-				Data: map[string]interface{}{
-					"text": DecodeCQCodeText(str[i:cqc[0]]),
-				},
-			}
-			segs = append(segs, seg)
+	return appendMessageSegmentsFromString(make([]MessageSegment, 0), str), nil
+}
+
+// appendMessageSegmentsFromString appends the segments parsed from str to segs.
+//
+// It scans str once: a CQ code spans from "[CQ:" to the first "]" after it,
+// and the content around CQ codes is text.
+func appendMessageSegmentsFromString(segs []MessageSegment, str string) []MessageSegment {
+	text := 0 // start of the text not appended yet
+	for text < len(str) {
+		start := strings.Index(str[text:], "[CQ:")
+		if start < 0 {
+			break
 		}
-		i = cqc[1]
-		seg, err := NewMessageSegmentFromCQCode(str[cqc[0]:cqc[1]])
-		if err != nil {
-			continue
+		start += text
+		end := strings.IndexByte(str[start+len("[CQ:"):], ']')
+		if end < 0 {
+			// An unclosed CQ code is text, and so is the rest
+			break
+		}
+		end += start + len("[CQ:") + 1
+		if start > text {
+			segs = append(segs, newTextSegment(str[text:start]))
+		}
+		seg, ok := parseCQCode(str[start:end])
+		if !ok {
+			// Invalid cqc is kept as text
+			seg = newTextSegment(str[start:end])
 		}
 		segs = append(segs, seg)
+		text = end
+	}
+	if len(str) > text {
+		segs = append(segs, newTextSegment(str[text:]))
+	}
+	return segs
+}
+
+// newTextSegment returns a text segment of the escaped str.
+func newTextSegment(str string) MessageSegment {
+	return MessageSegment{
+		Type: "text",
+		Data: map[string]interface{}{
+			"text": DecodeCQCodeText(str),
+		},
+	}
+}
+
+// parseCQCode parses str, which starts with "[CQ:" and ends with "]", to a MessageSegment.
+// It returns false if the function name is missing, or str contains "[" other than the leading one.
+//
+// Key-value pairs without "=" or with an empty key are ignored,
+// and the last one wins if a key is duplicated.
+func parseCQCode(str string) (MessageSegment, bool) {
+	body := str[len("[CQ:") : len(str)-1]
+	if strings.IndexByte(body, '[') >= 0 {
+		return MessageSegment{}, false
+	}
+	i := strings.IndexByte(body, ',')
+	if i < 0 {
+		i = len(body)
+	}
+	if i == 0 {
+		return MessageSegment{}, false
+	}
+	seg := MessageSegment{
+		Type: body[:i],
+		Data: make(map[string]interface{}),
 	}
-	if len(str) > i {
-		// There is a text message after all cqc
-		seg := MessageSegment{
-			Type: "text",
-			Data: map[string]interface{}{
-				"text": DecodeCQCodeText(str[i:]),
-			},
+	for i < len(body) {
+		body = body[i+1:]
+		i = strings.IndexByte(body, ',')
+		if i < 0 {
+			i = len(body)
 		}
-		segs = append(segs, seg)
+		kv := body[:i]
+		eq := strings.IndexByte(kv, '=')
+		if eq <= 0 {
+			continue
+		}
+		seg.Data[kv[:eq]] = DecodeCQCodeText(kv[eq+1:])
 	}
-	return segs, nil
+	return seg, true
 }
 
 // ParseMessageFromString parses msg as type string to a Message.
 // msg is the value of key "message" of the data umarshalled from the
 // API response JSON.
+//
+// Like ParseMessageSegmentsFromString, it never fails.
 func ParseMessageFromString(str string) (Message, error) {
-	segs, _ := ParseMessageSegmentsFromString(str)
-	return ParseMessageFromMessageSegments(segs), nil
+	p := segmentsPool.Get().(*[]MessageSegment)
+	segs := appendMessageSegmentsFromString((*p)[:0], str)
+	message := ParseMessageFromMessageSegments(segs)
+	for i := range segs {
+		segs[i] = MessageSegment{}
+	}
+	*p = segs[:0]
+	segmentsPool.Put(p)
+	return message, nil
 }
 
 // ParseMessageFromMessageSegments parses a sort of MessageSegment to a Message.
@@ -170,6 +257,11 @@ func ParseMessageFromMessageSegments(segs []MessageSegment) Message {
 	for _, seg := range segs {
 		switch seg.Type {
 		case "text":
+			if str, ok := seg.Data["text"].(string); ok {
+				// fast path without decoding
+				message = append(message, &Text{Text: str})
+				continue
+			}
 			text := Text{}
 			seg.ParseMedia(&text)
 			message = append(message, &text)
@@ -213,6 +305,10 @@ func ParseMessageFromMessageSegments(segs []MessageSegment) Message {
 			shake := Shake{}
 			seg.ParseMedia(&shake)
 			message = append(message, &shake)
+		case "poke":
+			poke := Poke{}
+			seg.ParseMedia(&poke)
+			message = append(message, &poke)
 		case "music":
 			music := Music{}
 			seg.ParseMedia(&music)
@@ -225,6 +321,10 @@ func ParseMessageFromMessageSegments(segs []MessageSegment) Message {
 			location := Location{}
 			seg.ParseMedia(&location)
 			message = append(message, &location)
+		case "contact":
+			contact := Contact{}
+			seg.ParseMedia(&contact)
+			message = append(message, &contact)
 		case "show":
 			show := Show{}
 			seg.ParseMedia(&show)
@@ -233,6 +333,14 @@ func ParseMessageFromMessageSegments(segs []MessageSegment) Message {
 			sign := Sign{}
 			seg.ParseMedia(&sign)
 			message = append(message, &sign)
+		case "xml":
+			card := XML{}
+			seg.ParseMedia(&card)
+			message = append(message, &card)
+		case "json":
+			card := JSON{}
+			seg.ParseMedia(&card)
+			message = append(message, &card)
 		case "rich":
 			rich := Rich{}
 			seg.ParseMedia(&rich)
@@ -241,6 +349,26 @@ func ParseMessageFromMessageSegments(segs []MessageSegment) Message {
 			hb := RedPack{}
 			seg.ParseMedia(&hb)
 			message = append(message, &hb)
+		case "redbag":
+			redbag := RedBag{}
+			seg.ParseMedia(&redbag)
+			message = append(message, &redbag)
+		case "gift":
+			gift := Gift{}
+			seg.ParseMedia(&gift)
+			message = append(message, &gift)
+		case "reply":
+			reply := Reply{}
+			seg.ParseMedia(&reply)
+			message = append(message, &reply)
+		case "forward":
+			forward := Forward{}
+			seg.ParseMedia(&forward)
+			message = append(message, &forward)
+		case "node":
+			node := Node{}
+			seg.ParseMedia(&node)
+			message = append(message, &node)
 		default:
 			s := seg
 			message = append(message, &s)
@@ -282,7 +410,7 @@ func Command(str string) (cmd string, args []string) {
 	str = strings.Replace(str, `\\`, `\0x5c`, -1)
 	str = strings.Replace(str, `\"`, `\0x22`, -1)
 	str = strings.Replace(str, `\'`, `\0x27`, -1)
-	strs := regexp.MustCompile(`'[\s\S]*?'|"[\s\S]*?"|\S*\[CQ:[\s\S]*?\]\S*|\S+`).FindAllString(str, -1)
+	strs := commandRegexp.FindAllString(str, -1)
 	if len(strs) == 0 || len(strs[0]) == 0 {
 		return
 	}
@@ -307,11 +435,11 @@ func Command(str string) (cmd string, args []string) {
 // CQString returns the CQEncoded string. All media in the message will be converted
 // to its CQCode.
 func (m *Message) CQString() string {
-	var str string
+	var buf bytes.Buffer
 	for _, media := range *m {
-		str += FormatCQCode(media)
+		buf.WriteString(FormatCQCode(media))
 	}
-	return str
+	return buf.String()
 }
 
 // MessageSegments returns an array of MessageSegment, you will find this useful if you
@@ -334,6 +462,71 @@ func (m *Message) Append(media Media) error {
 	return nil
 }
 
+// FilterByType returns the media in m whose function name is name, e.g. "image".
+func (m *Message) FilterByType(name string) Message {
+	res := make(Message, 0)
+	for _, media := range *m {
+		if media.FunctionName() == name {
+			res = append(res, media)
+		}
+	}
+	return res
+}
+
+// Images returns the images in m.
+func (m *Message) Images() []*Image {
+	res := make([]*Image, 0)
+	for _, media := range *m {
+		if v, ok := media.(*Image); ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// Mentions returns the mentions in m.
+func (m *Message) Mentions() []*At {
+	res := make([]*At, 0)
+	for _, media := range *m {
+		if v, ok := media.(*At); ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// MentionsUser returns if the user of id is mentioned in m.
+func (m *Message) MentionsUser(id int64) bool {
+	qq := strconv.FormatInt(id, 10)
+	for _, at := range m.Mentions() {
+		if at.QQ == qq {
+			return true
+		}
+	}
+	return false
+}
+
+// MentionsAll returns if all members are mentioned in m, i.e. [CQ:at,qq=all].
+func (m *Message) MentionsAll() bool {
+	for _, at := range m.Mentions() {
+		if at.QQ == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// Faces returns the faces in m.
+func (m *Message) Faces() []*Face {
+	res := make([]*Face, 0)
+	for _, media := range *m {
+		if v, ok := media.(*Face); ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
 // ParseMedia parses a MessageSegment to a specified type of Media.
 func (seg *MessageSegment) ParseMedia(media Media) error {
 	_, ok := media.(*MessageSegment)
@@ -341,18 +534,23 @@ func (seg *MessageSegment) ParseMedia(media Media) error {
 		reflect.ValueOf(media).Elem().Set(reflect.ValueOf(seg).Elem())
 		return nil
 	}
-	err := decode(seg.Data, media)
 	if seg.Type != media.FunctionName() {
-		if err != nil {
-			err = errors.Wrap(err, "wrong media type")
-		} else {
-			err = errors.New("wrong media type")
-		}
+		return ErrWrongMediaType
 	}
-	return err
-}
-
-// ParseMedia parses a CQEncoded string to a specified type of Media.
+	return decode(seg.Data, media)
+}
+
+// ParseCQCode parses a CQEncoded string to a specified type of Media.
+//
+// A string not starting with "[CQ:" and ending with "]" is plain text:
+// it is parsed into *Text and *MessageSegment as text, and ErrInvalidCQCode
+// is returned for other media. ErrInvalidCQCode is also returned if the
+// function name is missing, or the string contains "[" other than the leading one.
+// ErrWrongMediaType is returned if the function name does not match the media,
+// or else an error of decoding the values, e.g. id=abc for *Face.
+//
+// Key-value pairs without "=" or with an empty key are ignored,
+// and the last one wins if a key is duplicated.
 func ParseCQCode(str string, media Media) error {
 	l := len(str)
 	if l <= 5 || str[:4] != "[CQ:" || str[len(str)-1:] != "]" {
@@ -368,25 +566,14 @@ func ParseCQCode(str string, media Media) error {
 			}
 			return nil
 		default:
-			err := errors.New("invalid cqcode")
-			return err
+			return ErrInvalidCQCode
 		}
 	}
-	str = str[4 : len(str)-1]
-	strs := strings.Split(str, ",")
-	ms := MessageSegment{
-		Type: strs[0],
-		Data: make(map[string]interface{}),
-	}
-	for i := 1; i < len(strs); i++ {
-		kvstrs := strings.Split(strs[i], "=")
-		if len(kvstrs) == 0 {
-			continue
-		}
-		ms.Data[kvstrs[0]] = DecodeCQCodeText(strings.Join(kvstrs[1:], "="))
+	ms, ok := parseCQCode(str)
+	if !ok {
+		return ErrInvalidCQCode
 	}
-	err := ms.ParseMedia(media)
-	return err
+	return ms.ParseMedia(media)
 }
 
 // CQString returns the CQCode of a MessageSegment.
@@ -407,10 +594,16 @@ func FormatCQCode(media Media) string {
 			text = EncodeCQText(text)
 			return text
 		}
+		keys := make([]string, 0, len(v.Data))
+		for k := range v.Data {
+			keys = append(keys, k)
+		}
+		// Keys are sorted so that the output is stable
+		sort.Strings(keys)
 		strs := make([]string, 0)
 		strs = append(strs, v.Type)
-		for k, v := range v.Data {
-			text := fmt.Sprint(v)
+		for _, k := range keys {
+			text := fmt.Sprint(v.Data[k])
 			text = EncodeCQCodeText(text)
 			kvs := fmt.Sprintf("%s=%s", k, text)
 			strs = append(strs, kvs)
@@ -445,10 +638,19 @@ func FormatCQCode(media Media) string {
 					continue
 				}
 				f := rv.Type().Field(i)
-				k := f.Tag.Get("cq")
+				k, opts := f.Tag.Get("cq"), ""
+				if i := strings.Index(k, ","); i >= 0 {
+					k, opts = k[:i], k[i+1:]
+				}
 				if k == "" {
 					k = f.Name
 				}
+				if strings.Contains(opts, "omitempty") && isZeroValue(frv) {
+					continue
+				}
+				if isNested(frv) {
+					continue
+				}
 				text := fmt.Sprint(frv)
 				text = EncodeCQCodeText(text)
 				kvs := fmt.Sprintf("%s=%s", k, text)
@@ -462,6 +664,34 @@ func FormatCQCode(media Media) string {
 	}
 }
 
+// isZeroValue reports whether v is the zero value of its type, fields tagged
+// with "omitempty" will be omitted in CQCode if they are.
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isNested reports whether v is a slice or a map, which could not be represented in CQ code.
+func isNested(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Map
+}
+
 // Media is any kind of media that could be contained in a message.
 type Media interface {
 	// FunctionName returns the "function name" defined by Coolq, see documentation at
@@ -527,12 +757,22 @@ func (s *Sface) FunctionName() string {
 type Image struct {
 	FileID string `cq:"file"`
 	URL    string `cq:"url"`
+	// go-cqhttp
+	Type    string `cq:"type,omitempty"`    // "flash" (闪照), "show" (秀图)
+	SubType int    `cq:"subType,omitempty"` // 0 for normal images, 1 for stickers when received
+	ID      int    `cq:"id,omitempty"`      // effect of "show" images, 40000-40005
+	Cache   string `cq:"cache,omitempty"`   // "1" or "0", use NetResource instead for NetImage
 }
 
 func (i *Image) FunctionName() string {
 	return "image"
 }
 
+// IsFlash returns if the image is a flash image (闪照).
+func (i *Image) IsFlash() bool {
+	return i.Type == "flash"
+}
+
 // Record
 type Record struct {
 	FileID string `cq:"file"`
@@ -577,9 +817,21 @@ func (s *Shake) FunctionName() string {
 	return "shake"
 }
 
+// 戳一戳 of modern clients, either to a user of QQ, or a legacy poke of Type and ID
+type Poke struct {
+	QQ   int64  `cq:"qq,omitempty"`
+	Type int    `cq:"type,omitempty"`
+	ID   int    `cq:"id,omitempty"`
+	Name string `cq:"name,omitempty"`
+}
+
+func (p *Poke) FunctionName() string {
+	return "poke"
+}
+
 // 音乐
 type Music struct {
-	Type string `cq:"type"` // qq, 163, xiami
+	Type string `cq:"type"` // qq, 163, xiami, kugou, kuwo, migu, custom
 	// non-custom music
 	MusicID string `cq:"id"` // id
 	// custom music
@@ -599,6 +851,47 @@ func (m *Music) IsCustomMusic() bool {
 	return m.Type == "custom"
 }
 
+// musicProviders are the types of music shared by MusicID.
+var musicProviders = map[string]bool{
+	"qq":    true,
+	"163":   true,
+	"xiami": true,
+	"kugou": true,
+	"kuwo":  true,
+	"migu":  true,
+}
+
+// Validate returns an error describing the missing fields of m, or its unknown type.
+// A custom music requires ShareURL, AudioURL and Title, and the others require MusicID.
+func (m *Music) Validate() error {
+	if m.IsCustomMusic() {
+		var missing []string
+		if m.ShareURL == "" {
+			missing = append(missing, "url")
+		}
+		if m.AudioURL == "" {
+			missing = append(missing, "audio")
+		}
+		if m.Title == "" {
+			missing = append(missing, "title")
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("custom music requires %s", strings.Join(missing, ", "))
+		}
+		return nil
+	}
+	if m.Type == "" {
+		return errors.New("music requires type")
+	}
+	if !musicProviders[m.Type] {
+		return fmt.Errorf("unknown music type %q", m.Type)
+	}
+	if m.MusicID == "" {
+		return fmt.Errorf("%s music requires id", m.Type)
+	}
+	return nil
+}
+
 // 分享链接
 type Share struct {
 	URL     string `cq:"url"`
@@ -624,6 +917,16 @@ func (l *Location) FunctionName() string {
 	return "location"
 }
 
+// 推荐好友/群
+type Contact struct {
+	Type string `cq:"type"` // qq, group
+	ID   int64  `cq:"id"`
+}
+
+func (c *Contact) FunctionName() string {
+	return "contact"
+}
+
 // 厘米秀
 type Show struct {
 	ID int `cq:"id"`
@@ -653,6 +956,78 @@ func (rp *RedPack) FunctionName() string {
 	return "hb"
 }
 
+// 红包 of go-cqhttp, which could only be received
+type RedBag struct {
+	Title string `cq:"title"`
+}
+
+func (rb *RedBag) FunctionName() string {
+	return "redbag"
+}
+
+// 礼物, which could only be sent in a group
+type Gift struct {
+	QQ int64 `cq:"qq"` // the member the gift is sent to
+	ID int   `cq:"id"` // 0-13
+}
+
+func (g *Gift) FunctionName() string {
+	return "gift"
+}
+
+// 回复
+type Reply struct {
+	ID int64 `cq:"id"` // MessageID of the message replied to
+}
+
+func (r *Reply) FunctionName() string {
+	return "reply"
+}
+
+// 合并转发
+type Forward struct {
+	ID string `cq:"id"` // ID to fetch the forward message with get_forward_msg
+	// Content is the nodes provided inline by some implementations when received,
+	// which could not be represented in CQ code.
+	Content interface{} `cq:"content,omitempty"`
+}
+
+func (f *Forward) FunctionName() string {
+	return "forward"
+}
+
+// 合并转发节点, either referring to a message of ID, or a custom node of Name, Uin and Content
+type Node struct {
+	ID      int64  `cq:"id,omitempty"` // MessageID of the message forwarded
+	Name    string `cq:"name,omitempty"`
+	Uin     int64  `cq:"uin,omitempty"`
+	Content string `cq:"content,omitempty"` // CQ code of the message
+}
+
+func (n *Node) FunctionName() string {
+	return "node"
+}
+
+// XML 卡片消息
+type XML struct {
+	Data  string `cq:"data"` // XML payload, escaped in CQ code as other values
+	ResID int    `cq:"resid,omitempty"`
+}
+
+func (x *XML) FunctionName() string {
+	return "xml"
+}
+
+// JSON 卡片消息, e.g. 小程序卡片
+type JSON struct {
+	Data  string `cq:"data"` // JSON payload, escaped in CQ code as other values
+	ResID int    `cq:"resid,omitempty"`
+}
+
+func (j *JSON) FunctionName() string {
+	return "json"
+}
+
 // 其他富媒体
 type Rich struct {
 }
@@ -695,11 +1070,14 @@ func DecodeCQCodeText(str string) string {
 	return str
 }
 
-// NewFaceFromName returns a face that corresponds to a given face name.
+// NewFaceFromName returns a face that corresponds to a given face name,
+// including the aliases registered with RegisterFaceAlias.
 func NewFaceFromName(str string) (*Face, error) {
 	str = strings.Trim(str, "/")
 	face := Face{}
+	faceMux.RLock()
 	fi, ok := stringFace[str]
+	faceMux.RUnlock()
 	if ok {
 		face.FaceID = fi
 		return &face, nil
@@ -709,13 +1087,45 @@ func NewFaceFromName(str string) (*Face, error) {
 
 // Name returns the name of a face
 func (f *Face) Name() (string, error) {
+	faceMux.RLock()
 	str, ok := faceString[f.FaceID]
+	faceMux.RUnlock()
 	if ok {
 		return str, nil
 	}
 	return strconv.Itoa(f.FaceID), errors.New("Unknown face")
 }
 
+// RegisterFaceAlias registers name as a name of the face of id for NewFaceFromName,
+// e.g. an English alias, which replaces the face of the name if any.
+// The name is also returned by Face.Name if the face has no name yet, e.g. the new faces of id > 213.
+func RegisterFaceAlias(name string, id int) {
+	faceMux.Lock()
+	defer faceMux.Unlock()
+	registerFaceAlias(name, id)
+}
+
+// RegisterFaceAliases registers the names of faces in aliases like RegisterFaceAlias,
+// e.g. a catalog loaded from a file.
+func RegisterFaceAliases(aliases map[string]int) {
+	faceMux.Lock()
+	defer faceMux.Unlock()
+	for name, id := range aliases {
+		registerFaceAlias(name, id)
+	}
+}
+
+func registerFaceAlias(name string, id int) {
+	name = strings.Trim(name, "/")
+	stringFace[name] = id
+	if _, ok := faceString[id]; !ok {
+		faceString[id] = name
+	}
+}
+
+// faceMux guards stringFace and faceString, which are extended by RegisterFaceAlias.
+var faceMux sync.RWMutex
+
 var stringFace = map[string]int{
 	"微笑":   14,
 	"撇嘴":   1,
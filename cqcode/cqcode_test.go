@@ -2,6 +2,7 @@ package cqcode
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -102,7 +103,7 @@ func TestParseMessageFromString(t *testing.T) {
 
 	jsonstr := string(res)
 
-	if string(res) == `[{"Text":"[he,ym"},{"QQ":"123,456"},{"FaceID":14},{"Text":" \nSee this awesome image, "},{"FileID":"1.jpg","URL":""},{"Text":" Isn't it cool? "},{},{"Text":"\n"}]` {
+	if string(res) == `[{"type":"text","data":{"text":"[he,ym"}},{"type":"at","data":{"qq":"123,456"}},{"type":"face","data":{"id":"14"}},{"type":"text","data":{"text":" \nSee this awesome image, "}},{"type":"image","data":{"file":"1.jpg","url":""}},{"type":"text","data":{"text":" Isn't it cool? "}},{"type":"shake","data":{}},{"type":"text","data":{"text":"\n"}}]` {
 		t.Log("Decode text passed")
 	} else {
 		t.Errorf("Decode text failed: %v", jsonstr)
@@ -129,7 +130,7 @@ func TestMessage_Append(t *testing.T) {
 
 	jsonstr := string(res)
 
-	if string(res) == `[{"Type":"custom","MusicID":"","ShareURL":"http://localhost:8080","AudioURL":"","Title":"","Content":"","Image":""}]` {
+	if string(res) == `[{"type":"music","data":{"audio":"","content":"","id":"","image":"","title":"","type":"custom","url":"http://localhost:8080"}}]` {
 		t.Log("Append music passed")
 	} else {
 		t.Errorf("Append music failed: %v", jsonstr)
@@ -222,3 +223,275 @@ argemoji`,
 	}
 
 }
+
+const benchmarkMessage = "&#91;he&#44;ym[CQ:at,qq=123456] 今天吃什么[CQ:face,id=14] \nSee this awesome image, [CQ:image,file=1.jpg,url=https://example.com/1.jpg] Isn't it cool? [CQ:shake]\n"
+
+func BenchmarkParseMessageFromString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseMessageFromString(benchmarkMessage)
+	}
+}
+
+func BenchmarkParseMessageFromString_LargeImage(b *testing.B) {
+	str := "look [CQ:image,file=base64://" + strings.Repeat("aGVsbG8gd29ybGQ=", 1<<16) + "] nice"
+	b.SetBytes(int64(len(str)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseMessageFromString(str)
+	}
+}
+
+func BenchmarkParseMessageFromString_Pathological(b *testing.B) {
+	str := strings.Repeat("[CQ:", 1<<12) + strings.Repeat("[", 1<<12)
+	b.SetBytes(int64(len(str)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseMessageFromString(str)
+	}
+}
+
+func BenchmarkMessage_CQString(b *testing.B) {
+	m, _ := ParseMessageFromString(benchmarkMessage)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.CQString()
+	}
+}
+
+func TestMessage_Render(t *testing.T) {
+	m, _ := ParseMessageFromString("[CQ:at,qq=123] <b>1.5</b>[CQ:face,id=14]\n[CQ:image,file=1.jpg,url=https://example.com/1.jpg]")
+
+	h := m.RenderHTML()
+	md := m.RenderMarkdown()
+	if h == `<span class="cq-at">@123</span> &lt;b&gt;1.5&lt;/b&gt;<span class="cq-face">/微笑</span><br><img class="cq-image" src="https://example.com/1.jpg" alt="[图片]">` &&
+		md == "@123 \\<b\\>1\\.5\\</b\\>/微笑\n![图片](https://example.com/1.jpg)" {
+		t.Log("Render passed")
+	} else {
+		t.Errorf("Render failed: %v %v", h, md)
+	}
+}
+
+func TestParseMessageFromString_Malformed(t *testing.T) {
+	mes, err := ParseMessageFromString("[CQ:image,file=[CQ:face,id=14]][CQ:][CQ:face,=1,id=14,x][CQ:shake")
+
+	res, _ := json.Marshal(mes)
+
+	jsonstr := string(res)
+
+	if err == nil && jsonstr == `[{"type":"text","data":{"text":"[CQ:image,file=[CQ:face,id=14]"}},{"type":"text","data":{"text":"]"}},{"type":"text","data":{"text":"[CQ:]"}},{"type":"face","data":{"id":"14"}},{"type":"text","data":{"text":"[CQ:shake"}}]` {
+		t.Log("Parse malformed passed")
+	} else {
+		t.Errorf("Parse malformed failed: %v %v", err, jsonstr)
+	}
+
+}
+
+func TestParseMessage_Forward(t *testing.T) {
+	message, err := ParseMessage([]interface{}{
+		map[string]interface{}{"type": "forward", "data": map[string]interface{}{"id": "abc"}},
+		map[string]interface{}{"type": "node", "data": map[string]interface{}{"name": "bot", "uin": "10000", "content": "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Parse forward failed: %v", err)
+	}
+	forward, ok1 := message[0].(*Forward)
+	node, ok2 := message[1].(*Node)
+	str := message.CQString()
+	if ok1 && ok2 && forward.ID == "abc" && node.Uin == 10000 && str == "[CQ:forward,id=abc][CQ:node,name=bot,uin=10000,content=hi]" {
+		t.Log("Parse forward passed")
+	} else {
+		t.Errorf("Parse forward failed: %v %v %v", message[0], message[1], str)
+	}
+}
+
+func TestParseMessageFromString_Poke(t *testing.T) {
+	message, err := ParseMessageFromString("[CQ:poke,type=126,id=2003,name=猜拳][CQ:poke,qq=10000]")
+	if err != nil {
+		t.Fatalf("Parse poke failed: %v", err)
+	}
+	legacy, ok1 := message[0].(*Poke)
+	poke, ok2 := message[1].(*Poke)
+	if ok1 && ok2 && legacy.Type == 126 && legacy.ID == 2003 && legacy.Name == "猜拳" && poke.QQ == 10000 && FormatCQCode(poke) == "[CQ:poke,qq=10000]" {
+		t.Log("Parse poke passed")
+	} else {
+		t.Errorf("Parse poke failed: %v %v", message[0], message[1])
+	}
+}
+
+func TestJSON_CQString(t *testing.T) {
+	card := JSON{Data: `{"app":"com.tencent.miniapp","meta":{"title":"[bili]"}}`}
+	str := FormatCQCode(&card)
+	var parsed JSON
+	err := ParseCQCode(str, &parsed)
+	if err == nil && str == `[CQ:json,data={"app":"com.tencent.miniapp"&#44;"meta":{"title":"&#91;bili&#93;"}}]` && parsed.Data == card.Data {
+		t.Log("Format JSON passed")
+	} else {
+		t.Errorf("Format JSON failed: %v %v %v", err, str, parsed.Data)
+	}
+}
+
+func TestParseMessageFromString_Gift(t *testing.T) {
+	message, err := ParseMessageFromString("[CQ:gift,qq=10000,id=8][CQ:redbag,title=恭喜发财]")
+	if err != nil {
+		t.Fatalf("Parse gift failed: %v", err)
+	}
+	gift, ok1 := message[0].(*Gift)
+	redbag, ok2 := message[1].(*RedBag)
+	if ok1 && ok2 && gift.QQ == 10000 && gift.ID == 8 && redbag.Title == "恭喜发财" {
+		t.Log("Parse gift passed")
+	} else {
+		t.Errorf("Parse gift failed: %v %v", message[0], message[1])
+	}
+}
+
+func TestParseMessageFromString_Contact(t *testing.T) {
+	message, err := ParseMessageFromString("[CQ:contact,type=group,id=10000][CQ:location,lat=39.9,lon=116.4,title=北京,content=天安门]")
+	if err != nil {
+		t.Fatalf("Parse contact failed: %v", err)
+	}
+	contact, ok1 := message[0].(*Contact)
+	location, ok2 := message[1].(*Location)
+	if ok1 && ok2 && contact.Type == "group" && contact.ID == 10000 && location.Latitude == 39.9 && location.Title == "北京" {
+		t.Log("Parse contact passed")
+	} else {
+		t.Errorf("Parse contact failed: %v %v", message[0], message[1])
+	}
+}
+
+func TestParseMessage_FlashImage(t *testing.T) {
+	message, err := ParseMessage([]interface{}{
+		map[string]interface{}{"type": "image", "data": map[string]interface{}{"file": "abc.image", "type": "flash", "subType": "1"}},
+	})
+	if err != nil {
+		t.Fatalf("Parse flash image failed: %v", err)
+	}
+	image, ok := message[0].(*Image)
+	if ok && image.IsFlash() && image.SubType == 1 && FormatCQCode(image) == "[CQ:image,file=abc.image,url=,type=flash,subType=1]" {
+		t.Log("Parse flash image passed")
+	} else {
+		t.Errorf("Parse flash image failed: %v", message[0])
+	}
+}
+
+func TestParseMessageFromStringStrict(t *testing.T) {
+	mes, err := ParseMessageFromStringStrict("&#91;hi&#93;[CQ:face,id=14][CQ:at,qq=123&#44;456]")
+	if err != nil || len(mes) != 3 {
+		t.Fatalf("Parse strict failed: %v %v", err, mes)
+	}
+	for str, offset := range map[string]int{
+		"hi [CQ:face,id=14":          3,
+		"[CQ:image,file=[CQ:face]]":  15,
+		"[CQ:]":                      4,
+		"[CQ:face,id]":               9,
+		"[CQ:face,=14]":              9,
+		"hi ] there":                 3,
+		"hi [there]":                 3,
+		"a &lt; b":                   2,
+		"[CQ:at,qq=1,name=&#45;bot]": 17,
+	} {
+		_, err := ParseMessageFromStringStrict(str)
+		if pe, ok := err.(*ParseError); !ok || pe.Offset != offset {
+			t.Errorf("Parse strict failed: %q %v", str, err)
+		}
+	}
+}
+
+func TestParseMessageSegmentsFromArray(t *testing.T) {
+	var msg interface{}
+	json.Unmarshal([]byte(`[{"type":"face","data":{"id":"14"}},{"type":"text","data":{"text":"hi"}}]`), &msg)
+	segs, err := ParseMessageSegmentsFromArray(msg)
+	if err == nil && len(segs) == 2 && segs[0].Type == "face" && segs[0].Data["id"] == "14" && segs[1].Data["text"] == "hi" {
+		t.Log("Parse array passed")
+	} else {
+		t.Errorf("Parse array failed: %v %v", err, segs)
+	}
+}
+
+func TestMessage_JSON(t *testing.T) {
+	m, _ := ParseMessageFromString("&#91;hi&#93;[CQ:face,id=14][CQ:image,file=1.jpg,url=https://example.com/1.jpg][CQ:unknown,a=b]")
+	b, err := json.Marshal(m)
+	var m2 Message
+	err2 := json.Unmarshal(b, &m2)
+	if err == nil && err2 == nil && string(b) == `[{"type":"text","data":{"text":"[hi]"}},{"type":"face","data":{"id":"14"}},{"type":"image","data":{"file":"1.jpg","url":"https://example.com/1.jpg"}},{"type":"unknown","data":{"a":"b"}}]` &&
+		m2.CQString() == m.CQString() {
+		t.Log("Message JSON passed")
+	} else {
+		t.Errorf("Message JSON failed: %v %v %s %v", err, err2, b, m2.CQString())
+	}
+}
+
+func TestMessage_Filters(t *testing.T) {
+	m, _ := ParseMessageFromString("[CQ:at,qq=123]hi[CQ:face,id=14][CQ:image,file=1.jpg][CQ:at,qq=all][CQ:image,file=2.jpg]")
+
+	images := m.Images()
+	mentions := m.Mentions()
+	faces := m.Faces()
+	texts := m.FilterByType("text")
+
+	if len(images) == 2 && images[1].FileID == "2.jpg" &&
+		len(mentions) == 2 && mentions[0].QQ == "123" && mentions[1].QQ == "all" &&
+		len(faces) == 1 && faces[0].FaceID == 14 &&
+		len(texts) == 1 && texts.CQString() == "hi" {
+		t.Log("Message filters passed")
+	} else {
+		t.Errorf("Message filters failed: %v %v %v %v", images, mentions, faces, texts)
+	}
+}
+
+func TestMessage_MentionsUser(t *testing.T) {
+	m, _ := ParseMessageFromString("[CQ:at,qq=123] hi")
+	all, _ := ParseMessageFromString("hi [CQ:at,qq=all]")
+
+	if m.MentionsUser(123) && !m.MentionsUser(456) && !m.MentionsAll() && all.MentionsAll() && !all.MentionsUser(123) {
+		t.Log("Mentions passed")
+	} else {
+		t.Errorf("Mentions failed: %v %v", m, all)
+	}
+}
+
+func TestMusic_Validate(t *testing.T) {
+	valid := []Music{
+		{Type: "kugou", MusicID: "1"},
+		{Type: "migu", MusicID: "1"},
+		{Type: "custom", ShareURL: "http://localhost", AudioURL: "http://localhost/1.mp3", Title: "t"},
+	}
+	invalid := map[string]Music{
+		"custom music requires audio, title": {Type: "custom", ShareURL: "http://localhost"},
+		"kuwo music requires id":             {Type: "kuwo"},
+		`unknown music type "spotify"`:       {Type: "spotify", MusicID: "1"},
+		"music requires type":                {MusicID: "1"},
+	}
+
+	for _, m := range valid {
+		if err := m.Validate(); err != nil {
+			t.Errorf("Validate music failed: %v %v", m, err)
+		}
+	}
+	for reason, m := range invalid {
+		if err := m.Validate(); err == nil || err.Error() != reason {
+			t.Errorf("Validate music failed: %v %v", m, err)
+		}
+	}
+}
+
+func TestRegisterFaceAlias(t *testing.T) {
+	RegisterFaceAlias("smile", 14)
+	RegisterFaceAliases(map[string]int{
+		"/doge": 179,
+		"测试新表情": 300,
+	})
+
+	smile, err1 := NewFaceFromName("/smile")
+	doge, err2 := NewFaceFromName("doge")
+	name, err3 := smile.Name()
+	newName, err4 := (&Face{FaceID: 300}).Name()
+
+	if err1 == nil && err2 == nil && err3 == nil && err4 == nil &&
+		smile.FaceID == 14 && doge.FaceID == 179 && name == "微笑" && newName == "测试新表情" {
+		t.Log("Register face alias passed")
+	} else {
+		t.Errorf("Register face alias failed: %v %v %v %v %v %v %v %v", smile, doge, name, newName, err1, err2, err3, err4)
+	}
+}
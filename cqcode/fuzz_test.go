@@ -0,0 +1,114 @@
+//go:build go1.18
+
+package cqcode
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func FuzzParseCQCode(f *testing.F) {
+	for _, seed := range []string{
+		"[CQ:face,id=14]",
+		"[CQ:image,file=1.jpg,url=https://example.com/1.jpg?a=b]",
+		"[CQ:at,qq=123&#44;456]",
+		"[CQ:]",
+		"[CQ:face,=14,id]",
+		"[CQ:image,file=[CQ:face,id=14]]",
+		"&#91;he&#44;ym",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, str string) {
+		var seg MessageSegment
+		if err := ParseCQCode(str, &seg); err != nil {
+			if err != ErrInvalidCQCode {
+				t.Errorf("unexpected error %v of %q", err, str)
+			}
+			return
+		}
+		if seg.Type == "" {
+			t.Errorf("empty type of %q", str)
+		}
+		var face Face
+		if err := ParseCQCode(str, &face); err == nil && seg.Type != "face" {
+			t.Errorf("%q parsed as face", str)
+		}
+	})
+}
+
+func FuzzParseMessageSegmentsFromString(f *testing.F) {
+	for _, seed := range []string{
+		benchmarkMessage,
+		"[CQ:face,id=14",
+		"[CQ:image,file=[CQ:face,id=14]]",
+		"[CQ:[CQ:]]",
+		"[CQ:shake,,=,a=b=c][",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, str string) {
+		segs, err := ParseMessageSegmentsFromString(str)
+		if err != nil {
+			t.Errorf("unexpected error %v of %q", err, str)
+		}
+		if str != "" && len(segs) == 0 {
+			t.Errorf("content of %q lost", str)
+		}
+		for _, seg := range segs {
+			if seg.Type == "" {
+				t.Errorf("empty type in %q", str)
+			}
+		}
+		// Formatting and parsing again is stable
+		m, _ := ParseMessageFromString(str)
+		s := m.CQString()
+		m2, _ := ParseMessageFromString(s)
+		if s2 := m2.CQString(); s2 != s {
+			t.Errorf("unstable %q: %q != %q", str, s, s2)
+		}
+	})
+}
+
+// regexpSegments is the regexp based parser replaced by the scanner,
+// which the scanner is checked against.
+func regexpSegments(str string) []MessageSegment {
+	segs := make([]MessageSegment, 0)
+	res := regexp.MustCompile(`\[CQ:[\s\S]*?\]`).FindAllStringIndex(str, -1)
+	i := 0
+	for _, cqc := range res {
+		if cqc[0] > i {
+			segs = append(segs, newTextSegment(str[i:cqc[0]]))
+		}
+		i = cqc[1]
+		seg, err := NewMessageSegmentFromCQCode(str[cqc[0]:cqc[1]])
+		if err != nil {
+			seg = newTextSegment(str[cqc[0]:cqc[1]])
+		}
+		segs = append(segs, seg)
+	}
+	if len(str) > i {
+		segs = append(segs, newTextSegment(str[i:]))
+	}
+	return segs
+}
+
+func FuzzParseMessageSegmentsFromString_Regexp(f *testing.F) {
+	for _, seed := range []string{
+		benchmarkMessage,
+		"[CQ:face,id=14",
+		"[CQ:image,file=[CQ:face,id=14]]",
+		"[CQ:[CQ:]]",
+		"[CQ:shake,,=,a=b=c][",
+		"[CQ:]]][CQ:,a=b]",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, str string) {
+		segs, _ := ParseMessageSegmentsFromString(str)
+		if want := regexpSegments(str); !reflect.DeepEqual(segs, want) {
+			t.Errorf("%q parsed as %v, want %v", str, segs, want)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package cqcode
+
+import (
+	"bytes"
+	"golang.org/x/image/draw"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF format for image.Decode
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// ImageOptions describes how an image is preprocessed before it is encoded in base64.
+type ImageOptions struct {
+	// MaxDimension is the limit of the longest side of the image in pixels,
+	// the image will be scaled down proportionally if it exceeds the limit.
+	// 0 means no limit.
+	MaxDimension int
+	// JPEGQuality (1-100), if set, re-encodes the image as JPEG with the quality.
+	JPEGQuality int
+}
+
+// preprocessImage resizes and re-encodes an image according to the options.
+//
+// Only JPEG and PNG images are processed, others (e.g. GIF, which might be animated)
+// are returned as they are.
+func preprocessImage(data []byte, options ImageOptions) ([]byte, error) {
+	if options.MaxDimension <= 0 && options.JPEGQuality <= 0 {
+		return data, nil
+	}
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png":
+	default:
+		return data, nil
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	resized := false
+	b := img.Bounds()
+	if max := options.MaxDimension; max > 0 && (b.Dx() > max || b.Dy() > max) {
+		w, h := max, max
+		if b.Dx() > b.Dy() {
+			h = b.Dy() * max / b.Dx()
+		} else {
+			w = b.Dx() * max / b.Dy()
+		}
+		if w < 1 {
+			w = 1
+		}
+		if h < 1 {
+			h = 1
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+		img = dst
+		resized = true
+	}
+
+	if !resized && options.JPEGQuality <= 0 {
+		return data, nil
+	}
+
+	buf := bytes.Buffer{}
+	if options.JPEGQuality > 0 || format == "jpeg" {
+		quality := options.JPEGQuality
+		if quality <= 0 || quality > 100 {
+			quality = jpeg.DefaultQuality
+		}
+		// JPEG has no alpha channel, so transparent pixels are flattened onto white
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		err = jpeg.Encode(&buf, flat, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
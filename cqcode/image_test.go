@@ -0,0 +1,24 @@
+package cqcode
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestPreprocessImage(t *testing.T) {
+	buf := bytes.Buffer{}
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 100)))
+
+	data, err := preprocessImage(buf.Bytes(), ImageOptions{MaxDimension: 200, JPEGQuality: 80})
+	if err != nil {
+		t.Fatalf("TestPreprocessImage failed: %v", err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err == nil && format == "jpeg" && cfg.Width == 200 && cfg.Height == 50 {
+		t.Log("TestPreprocessImage passed")
+	} else {
+		t.Errorf("TestPreprocessImage failed: %v %v %v", format, cfg, err)
+	}
+}
@@ -0,0 +1,49 @@
+package cqcode
+
+import "encoding/json"
+
+// MarshalJSON encodes the message as an array of segments, in the form posted by CQ HTTP
+// with message_post_format set to array, whose values are strings.
+func (m Message) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	segs := make([]MessageSegment, 0, len(m))
+	for _, media := range m {
+		switch v := media.(type) {
+		case *MessageSegment:
+			segs = append(segs, *v)
+		case *Text:
+			segs = append(segs, MessageSegment{
+				Type: "text",
+				Data: map[string]interface{}{"text": v.Text},
+			})
+		default:
+			seg, err := NewMessageSegmentFromCQCode(FormatCQCode(media))
+			if err != nil {
+				return nil, err
+			}
+			segs = append(segs, seg)
+		}
+	}
+	return json.Marshal(segs)
+}
+
+// UnmarshalJSON decodes the message from an array of segments, or a CQ string.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*m, _ = ParseMessageFromString(str)
+		return nil
+	}
+	var segs []MessageSegment
+	if err := json.Unmarshal(data, &segs); err != nil {
+		return err
+	}
+	if segs == nil {
+		*m = nil
+		return nil
+	}
+	*m = ParseMessageFromMessageSegments(segs)
+	return nil
+}
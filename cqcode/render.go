@@ -0,0 +1,151 @@
+package cqcode
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// RenderHTML renders the message in HTML, displaying images inline and
+// mentions, faces and other segments as readable text,
+// e.g. for web dashboards and log viewers.
+func (m *Message) RenderHTML() string {
+	var buf bytes.Buffer
+	for _, media := range *m {
+		switch v := media.(type) {
+		case *Text:
+			buf.WriteString(strings.Replace(html.EscapeString(v.Text), "\n", "<br>", -1))
+		case *Image:
+			renderHTMLImage(&buf, v)
+		case *NetImage:
+			renderHTMLImage(&buf, v.Image)
+		case *Music:
+			renderHTMLLink(&buf, media, v.ShareURL, v.Title)
+		case *Share:
+			renderHTMLLink(&buf, media, v.URL, v.Title)
+		default:
+			buf.WriteString(`<span class="cq-` + media.FunctionName() + `">`)
+			buf.WriteString(html.EscapeString(renderText(media)))
+			buf.WriteString(`</span>`)
+		}
+	}
+	return buf.String()
+}
+
+func renderHTMLImage(buf *bytes.Buffer, image *Image) {
+	buf.WriteString(`<img class="cq-image" src="`)
+	buf.WriteString(html.EscapeString(imageSource(image)))
+	buf.WriteString(`" alt="[图片]">`)
+}
+
+func renderHTMLLink(buf *bytes.Buffer, media Media, url string, title string) {
+	if title == "" {
+		title = renderText(media)
+	}
+	buf.WriteString(`<a class="cq-` + media.FunctionName() + `" href="`)
+	buf.WriteString(html.EscapeString(url))
+	buf.WriteString(`">`)
+	buf.WriteString(html.EscapeString(title))
+	buf.WriteString(`</a>`)
+}
+
+// markdownEscaper escapes the characters reserved in Markdown,
+// including the ones in MarkdownV2 of Telegram.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `{`, `\{`, `}`, `\}`,
+	`[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`, `#`, `\#`, `+`, `\+`,
+	`-`, `\-`, `.`, `\.`, `!`, `\!`, `|`, `\|`, `<`, `\<`, `>`, `\>`, `~`, `\~`, `=`, `\=`,
+)
+
+// RenderMarkdown renders the message in Markdown, displaying images inline and
+// mentions, faces and other segments as readable text,
+// e.g. for bridges to Telegram or Discord.
+func (m *Message) RenderMarkdown() string {
+	var buf bytes.Buffer
+	for _, media := range *m {
+		switch v := media.(type) {
+		case *Text:
+			buf.WriteString(markdownEscaper.Replace(v.Text))
+		case *Image:
+			buf.WriteString("![图片](" + imageSource(v) + ")")
+		case *NetImage:
+			buf.WriteString("![图片](" + imageSource(v.Image) + ")")
+		case *Music:
+			renderMarkdownLink(&buf, media, v.ShareURL, v.Title)
+		case *Share:
+			renderMarkdownLink(&buf, media, v.URL, v.Title)
+		default:
+			buf.WriteString(markdownEscaper.Replace(renderText(media)))
+		}
+	}
+	return buf.String()
+}
+
+func renderMarkdownLink(buf *bytes.Buffer, media Media, url string, title string) {
+	if title == "" {
+		title = renderText(media)
+	}
+	buf.WriteString("[" + markdownEscaper.Replace(title) + "](" + url + ")")
+}
+
+// imageSource returns the URL of an image, falling back to its file.
+func imageSource(image *Image) string {
+	if image.URL != "" {
+		return image.URL
+	}
+	return image.FileID
+}
+
+// renderText returns a readable text of a segment.
+func renderText(media Media) string {
+	switch v := media.(type) {
+	case *Text:
+		return v.Text
+	case *At:
+		if v.QQ == "all" {
+			return "@全体成员"
+		}
+		return "@" + v.QQ
+	case *Face:
+		name, _ := v.Name()
+		return "/" + name
+	case *Emoji:
+		return string(rune(v.EmojiID))
+	case *Location:
+		return "[位置] " + v.Title + " " + v.Content
+	case *Reply:
+		return "[回复]"
+	case *Contact:
+		if v.Type == "group" {
+			return "[推荐群] " + strconv.FormatInt(v.ID, 10)
+		}
+		return "[推荐好友] " + strconv.FormatInt(v.ID, 10)
+	case *Forward:
+		return "[合并转发]"
+	case *XML, *JSON:
+		return "[卡片]"
+	case *Record, *NetRecord:
+		return "[语音]"
+	case *Music:
+		return "[音乐]"
+	case *Share:
+		return "[分享]"
+	case *Dice:
+		return "[骰子] " + strconv.Itoa(v.Type)
+	case *Rps:
+		return "[猜拳]"
+	case *Shake, *Poke:
+		return "[戳一戳]"
+	case *RedPack:
+		return "[红包] " + v.Title
+	case *RedBag:
+		return "[红包] " + v.Title
+	case *Gift:
+		return "[礼物]"
+	case *Image, *NetImage:
+		return "[图片]"
+	default:
+		return "[" + media.FunctionName() + "]"
+	}
+}
@@ -0,0 +1,290 @@
+package cqcode
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	cacheEnabled  = 1
+	cacheDisabled = 0
+)
+
+// NetResource is a resource located in the Internet.
+type NetResource struct {
+	Cache   int    `cq:"cache"`
+	Proxy   string `cq:"proxy,omitempty"`   // "1" or "0", whether to download via the proxy of CQ HTTP
+	Timeout int    `cq:"timeout,omitempty"` // timeout of downloading in seconds
+}
+
+// EnableCache enables CQ HTTP's cache feature.
+func (r *NetResource) EnableCache() {
+	r.Cache = cacheEnabled
+}
+
+// DisableCache forces CQ HTTP download from the URL instead of using cache.
+func (r *NetResource) DisableCache() {
+	r.Cache = cacheDisabled
+}
+
+// ViaProxy sets whether CQ HTTP downloads the resource via its proxy,
+// which is left to the default of CQ HTTP if not set.
+func (r *NetResource) ViaProxy(enable bool) {
+	if enable {
+		r.Proxy = "1"
+	} else {
+		r.Proxy = "0"
+	}
+}
+
+// WithTimeout sets the timeout of downloading the resource,
+// which is rounded up to seconds.
+func (r *NetResource) WithTimeout(timeout time.Duration) {
+	r.Timeout = int((timeout + time.Second - 1) / time.Second)
+}
+
+// NetImage is an image located in the Internet.
+type NetImage struct {
+	*Image
+	*NetResource
+}
+
+// NetRecord is a record located in the Internet.
+type NetRecord struct {
+	*Record
+	*NetResource
+}
+
+// NewImageBase64 formats an image in base64.
+//
+// The image will be preprocessed before encoding if options are given,
+// and then validated against DefaultUploadLimits.
+func NewImageBase64(file interface{}, options ...ImageOptions) (*Image, error) {
+	data, err := readFile(file)
+	if err != nil {
+		return &Image{}, err
+	}
+	if len(options) > 0 {
+		data, err = preprocessImage(data, options[0])
+		if err != nil {
+			return &Image{}, err
+		}
+	}
+	if err := ValidateImage(data); err != nil {
+		return &Image{}, err
+	}
+	return &Image{
+		FileID: encodeBase64(data),
+	}, nil
+}
+
+// NewRecordBase64 formats a record in base64,
+// which will be validated against DefaultUploadLimits.
+func NewRecordBase64(file interface{}) (*Record, error) {
+	data, err := readFile(file)
+	if err != nil {
+		return &Record{}, err
+	}
+	if err := ValidateRecord(data); err != nil {
+		return &Record{}, err
+	}
+	return &Record{
+		FileID: encodeBase64(data),
+	}, nil
+}
+
+// NewFileBase64 formats a file into base64 format.
+func NewFileBase64(file interface{}) (string, error) {
+	data, err := readFile(file)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateFile(data); err != nil {
+		return "", err
+	}
+	return encodeBase64(data), nil
+}
+
+// readFile reads the content of a file, which could be a path, []byte or io.Reader.
+func readFile(file interface{}) ([]byte, error) {
+	switch f := file.(type) {
+	case string:
+		return ioutil.ReadFile(f)
+	case []byte:
+		return f, nil
+	case io.Reader:
+		return ioutil.ReadAll(f)
+	default:
+		return nil, errors.New("bad file type")
+	}
+}
+
+func encodeBase64(data []byte) string {
+	return "base64://" + base64.StdEncoding.EncodeToString(data)
+}
+
+// NewImageLocal formats an image with the file path,
+// this requires CQ HTTP runs in the same host with your bot.
+//
+// This method is deprecated and will get removed, see #11.
+// Please use NewImageWeb instead.
+func NewImageLocal(file string) *Image {
+	return &Image{
+		FileID: NewFileLocal(file),
+	}
+}
+
+// NewRecordLocal formats a record with the file path,
+// this requires CQ HTTP runs in the same host with your bot.
+//
+// This method is deprecated and will get removed, see #11.
+// Please use NewRecordWeb instead.
+func NewRecordLocal(file string) *Record {
+	return &Record{
+		FileID: NewFileLocal(file),
+	}
+}
+
+// NewFileLocal formats a file with the file path, returning the string.
+//
+// This method is deprecated and will get removed, see #11.
+// Please use NewFileWeb instead.
+func NewFileLocal(file string) string {
+	return "file://" + file
+}
+
+// NewImageWeb formats an image with the URL.
+func NewImageWeb(url *url.URL) *NetImage {
+	return &NetImage{
+		Image: &Image{
+			FileID: url.String(),
+		},
+		NetResource: &NetResource{
+			Cache: cacheEnabled,
+		},
+	}
+}
+
+// NewRecordWeb formats a record with the URL.
+func NewRecordWeb(url *url.URL) *NetRecord {
+	return &NetRecord{
+		Record: &Record{
+			FileID: url.String(),
+		},
+		NetResource: &NetResource{
+			Cache: cacheEnabled,
+		},
+	}
+}
+
+// NewImageURL formats an image with the URL, which could be a string, *url.URL or url.URL.
+//
+// Besides http(s) and file URLs, a data URI is accepted and will be converted into base64.
+func NewImageURL(u interface{}) (*NetImage, error) {
+	file, err := formatResourceURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return &NetImage{
+		Image: &Image{
+			FileID: file,
+		},
+		NetResource: &NetResource{
+			Cache: cacheEnabled,
+		},
+	}, nil
+}
+
+// NewRecordURL formats a record with the URL, which could be a string, *url.URL or url.URL.
+//
+// Besides http(s) and file URLs, a data URI is accepted and will be converted into base64.
+func NewRecordURL(u interface{}) (*NetRecord, error) {
+	file, err := formatResourceURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return &NetRecord{
+		Record: &Record{
+			FileID: file,
+		},
+		NetResource: &NetResource{
+			Cache: cacheEnabled,
+		},
+	}, nil
+}
+
+// NewImageFromHTTPResponse downloads an image from the response
+// and formats it in base64, the response body will be closed.
+func NewImageFromHTTPResponse(resp *http.Response, options ...ImageOptions) (*Image, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &Image{}, fmt.Errorf("failed to download image: %s", resp.Status)
+	}
+	return NewImageBase64(resp.Body, options...)
+}
+
+// formatResourceURL validates a URL and formats it as the file of a media.
+func formatResourceURL(u interface{}) (string, error) {
+	var str string
+	switch v := u.(type) {
+	case *url.URL:
+		if v == nil {
+			return "", errors.New("nil url")
+		}
+		str = v.String()
+	case url.URL:
+		str = v.String()
+	case string:
+		str = v
+	default:
+		return "", errors.New("bad url type")
+	}
+
+	if strings.HasPrefix(str, "data:") {
+		return formatDataURI(str)
+	}
+	if strings.HasPrefix(str, "base64://") {
+		return str, nil
+	}
+	parsed, err := url.Parse(str)
+	if err != nil {
+		return "", err
+	}
+	switch parsed.Scheme {
+	case "http", "https":
+		if parsed.Host == "" {
+			return "", errors.New("invalid url: missing host")
+		}
+	case "file":
+	default:
+		return "", fmt.Errorf("invalid url: unsupported scheme %q", parsed.Scheme)
+	}
+	return parsed.String(), nil
+}
+
+// formatDataURI converts a data URI, i.e. data:[<mediatype>][;base64],<data>, into base64 format.
+func formatDataURI(str string) (string, error) {
+	i := strings.Index(str, ",")
+	if i < 0 {
+		return "", errors.New("invalid data uri")
+	}
+	meta, data := str[len("data:"):i], str[i+1:]
+	if strings.HasSuffix(meta, ";base64") {
+		if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+			return "", err
+		}
+		return "base64://" + data, nil
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return "", err
+	}
+	return encodeBase64([]byte(decoded)), nil
+}
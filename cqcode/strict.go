@@ -0,0 +1,136 @@
+package cqcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError is returned by the strict parsing functions, e.g. ParseMessageFromStringStrict,
+// describing the first problem found in the input.
+type ParseError struct {
+	Offset int // byte offset of the problem in the input
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Reason, e.Offset)
+}
+
+// cqEscapes are the escape sequences accepted in both text and values.
+var cqEscapes = []string{"&amp;", "&#91;", "&#93;", "&#44;"}
+
+// ParseMessageSegmentsFromStringStrict parses str like ParseMessageSegmentsFromString,
+// but returns a *ParseError instead of keeping malformed content as text, e.g. of user input.
+//
+// The problems reported are an unclosed or nested CQ code, a CQ code without function name,
+// a key-value pair without "=" or key, an unescaped "[" or "]" in text,
+// and an escape sequence other than "&amp;", "&#91;", "&#93;" and "&#44;".
+func ParseMessageSegmentsFromStringStrict(str string) ([]MessageSegment, error) {
+	segs := make([]MessageSegment, 0)
+	i := 0
+	for i < len(str) {
+		start := strings.IndexAny(str[i:], "[]")
+		if start < 0 {
+			start = len(str)
+		} else {
+			start += i
+		}
+		if start > i {
+			if err := checkEscapes(str[i:start], i); err != nil {
+				return nil, err
+			}
+			segs = append(segs, MessageSegment{
+				Type: "text",
+				Data: map[string]interface{}{
+					"text": DecodeCQCodeText(str[i:start]),
+				},
+			})
+		}
+		if start == len(str) {
+			break
+		}
+		if str[start] == ']' {
+			return nil, &ParseError{Offset: start, Reason: `unescaped "]"`}
+		}
+		if !strings.HasPrefix(str[start:], "[CQ:") {
+			return nil, &ParseError{Offset: start, Reason: `unescaped "["`}
+		}
+		end := strings.IndexAny(str[start+1:], "[]")
+		if end < 0 {
+			return nil, &ParseError{Offset: start, Reason: "unclosed cqcode"}
+		}
+		end += start + 1
+		if str[end] == '[' {
+			return nil, &ParseError{Offset: end, Reason: "nested cqcode"}
+		}
+		seg, err := parseCQCodeStrict(str, start, end)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+		i = end + 1
+	}
+	return segs, nil
+}
+
+// ParseMessageFromStringStrict parses str like ParseMessageFromString, but returns
+// a *ParseError if str is malformed, see ParseMessageSegmentsFromStringStrict.
+func ParseMessageFromStringStrict(str string) (Message, error) {
+	segs, err := ParseMessageSegmentsFromStringStrict(str)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMessageFromMessageSegments(segs), nil
+}
+
+// parseCQCodeStrict parses the CQ code in str from "[" at start to "]" at end.
+func parseCQCodeStrict(str string, start int, end int) (MessageSegment, error) {
+	offset := start + len("[CQ:")
+	parts := strings.Split(str[offset:end], ",")
+	if parts[0] == "" {
+		return MessageSegment{}, &ParseError{Offset: offset, Reason: "missing function name"}
+	}
+	seg := MessageSegment{
+		Type: parts[0],
+		Data: make(map[string]interface{}),
+	}
+	offset += len(parts[0]) + 1
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		switch {
+		case len(kv) != 2:
+			return MessageSegment{}, &ParseError{Offset: offset, Reason: `missing "="`}
+		case kv[0] == "":
+			return MessageSegment{}, &ParseError{Offset: offset, Reason: "missing key"}
+		}
+		if err := checkEscapes(kv[1], offset+len(kv[0])+1); err != nil {
+			return MessageSegment{}, err
+		}
+		seg.Data[kv[0]] = DecodeCQCodeText(kv[1])
+		offset += len(part) + 1
+	}
+	return seg, nil
+}
+
+// checkEscapes returns a *ParseError if an unknown escape sequence is found in s,
+// which is at offset of the input.
+func checkEscapes(s string, offset int) error {
+	for i := strings.IndexByte(s, '&'); i >= 0; {
+		known := false
+		for _, escape := range cqEscapes {
+			if strings.HasPrefix(s[i:], escape) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return &ParseError{Offset: offset + i, Reason: "unknown escape"}
+		}
+		next := strings.IndexByte(s[i+1:], '&')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package cqcode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UploadLimits are the limits of files checked before they are sent,
+// a limit is not checked if it is 0.
+type UploadLimits struct {
+	MaxImageSize  int64
+	MaxRecordSize int64
+	MaxFileSize   int64
+	// MaxRecordDuration is only checked for WAV records,
+	// since the duration of other formats can not be known without decoding.
+	MaxRecordDuration time.Duration
+}
+
+// DefaultUploadLimits is used by the image, record and file constructors.
+var DefaultUploadLimits = UploadLimits{
+	MaxImageSize:      30 << 20,
+	MaxRecordSize:     20 << 20,
+	MaxFileSize:       0,
+	MaxRecordDuration: 60 * time.Second,
+}
+
+// UploadError is returned when a file fails the validation before it is sent.
+type UploadError struct {
+	Kind   string // "image", "record" or "file"
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *UploadError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Kind, e.Reason)
+}
+
+// ValidateImage checks an image against the DefaultUploadLimits.
+func ValidateImage(data []byte) error {
+	if err := validateSize("image", int64(len(data)), DefaultUploadLimits.MaxImageSize); err != nil {
+		return err
+	}
+	if ct := http.DetectContentType(data); !strings.HasPrefix(ct, "image/") {
+		return &UploadError{Kind: "image", Reason: "unsupported content type " + ct}
+	}
+	return nil
+}
+
+// ValidateRecord checks a record against the DefaultUploadLimits.
+func ValidateRecord(data []byte) error {
+	if err := validateSize("record", int64(len(data)), DefaultUploadLimits.MaxRecordSize); err != nil {
+		return err
+	}
+	ct := http.DetectContentType(data)
+	if strings.HasPrefix(ct, "image/") || strings.HasPrefix(ct, "video/") || strings.HasPrefix(ct, "text/") {
+		return &UploadError{Kind: "record", Reason: "unsupported content type " + ct}
+	}
+	if max := DefaultUploadLimits.MaxRecordDuration; max > 0 {
+		if d, ok := wavDuration(data); ok && d > max {
+			return &UploadError{Kind: "record", Reason: fmt.Sprintf("too long (%v > %v)", d, max)}
+		}
+	}
+	return nil
+}
+
+// ValidateFile checks a file against the DefaultUploadLimits.
+func ValidateFile(data []byte) error {
+	return validateSize("file", int64(len(data)), DefaultUploadLimits.MaxFileSize)
+}
+
+func validateSize(kind string, size int64, limit int64) error {
+	if limit > 0 && size > limit {
+		return &UploadError{
+			Kind:   kind,
+			Reason: fmt.Sprintf("too large (%s > %s)", formatSize(size), formatSize(limit)),
+		}
+	}
+	if size == 0 {
+		return &UploadError{Kind: kind, Reason: "empty content"}
+	}
+	return nil
+}
+
+func formatSize(size int64) string {
+	if size < 1<<20 {
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+}
+
+// wavDuration reads the duration of a WAV file from its header.
+func wavDuration(data []byte) (time.Duration, bool) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return 0, false
+	}
+	var byteRate uint32
+	for i := 12; i+8 <= len(data); {
+		id := string(data[i : i+4])
+		size := binary.LittleEndian.Uint32(data[i+4 : i+8])
+		switch id {
+		case "fmt ":
+			if i+20 > len(data) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(data[i+16 : i+20])
+		case "data":
+			if byteRate == 0 {
+				return 0, false
+			}
+			return time.Duration(float64(size) / float64(byteRate) * float64(time.Second)), true
+		}
+		i += 8 + int(size) + int(size%2)
+	}
+	return 0, false
+}
@@ -0,0 +1,14 @@
+package cqcode
+
+import (
+	"testing"
+)
+
+func TestValidateImage(t *testing.T) {
+	err := ValidateImage([]byte("hello"))
+	if err != nil && err.Error() == "invalid image: unsupported content type text/plain; charset=utf-8" {
+		t.Log("TestValidateImage passed")
+	} else {
+		t.Errorf("TestValidateImage failed: %v", err)
+	}
+}
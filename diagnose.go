@@ -0,0 +1,179 @@
+package qqbotapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DiagnosticCheck is the result of a probe made by Diagnose.
+type DiagnosticCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+	Err    error
+}
+
+// DiagnosticReport is a report of what works and what is misconfigured.
+type DiagnosticReport struct {
+	Endpoint string
+	Checks   []DiagnosticCheck
+}
+
+// OK returns if all the checks passed.
+func (r DiagnosticReport) OK() bool {
+	for _, c := range r.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// String displays the report in lines.
+func (r DiagnosticReport) String() string {
+	lines := []string{"Diagnostic report of " + r.Endpoint}
+	for _, c := range r.Checks {
+		mark := "OK"
+		if !c.OK {
+			mark = "FAIL"
+		}
+		line := fmt.Sprintf("[%s] %s: %s", mark, c.Name, c.Detail)
+		if c.Err != nil {
+			line += fmt.Sprintf(" (%v)", c.Err)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diagnose probes the API endpoint, and returns a report of what works and what is misconfigured.
+//
+// Probes stop when ctx is done, and the remaining checks will be reported as failed.
+func (bot *BotAPI) Diagnose(ctx context.Context) DiagnosticReport {
+	report := DiagnosticReport{Endpoint: bot.APIEndpoint}
+	add := func(c DiagnosticCheck) {
+		report.Checks = append(report.Checks, c)
+	}
+
+	u, err := url.Parse(bot.APIEndpoint)
+	if err != nil {
+		add(DiagnosticCheck{Name: "endpoint", Detail: "invalid api endpoint", Err: err})
+		return report
+	}
+	switch u.Scheme {
+	case "http", "https":
+		add(DiagnosticCheck{Name: "endpoint", OK: true, Detail: "using HTTP"})
+		add(bot.diagnoseHTTPAuth(ctx))
+	case "ws", "wss":
+		add(DiagnosticCheck{Name: "endpoint", OK: true, Detail: "using WebSocket"})
+		add(bot.diagnoseWebSocket())
+	default:
+		add(DiagnosticCheck{Name: "endpoint", Detail: "bad api url scheme " + u.Scheme})
+		return report
+	}
+
+	resp, err := bot.probe(ctx, "get_login_info")
+	if err != nil {
+		add(DiagnosticCheck{Name: "login", Detail: "failed to get login info", Err: err})
+	} else {
+		var user User
+		if err := json.Unmarshal(resp.Data, &user); err != nil || user.ID == 0 {
+			add(DiagnosticCheck{Name: "login", Detail: "unexpected login info " + string(resp.Data), Err: err})
+		} else {
+			add(DiagnosticCheck{Name: "login", OK: true, Detail: fmt.Sprintf("logged in as %s (%d)", user.NickName, user.ID)})
+		}
+	}
+
+	resp, err = bot.probe(ctx, "get_version_info")
+	if err != nil {
+		add(DiagnosticCheck{Name: "version", Detail: "failed to get version info", Err: err})
+	} else {
+		var version VersionInfo
+		if err := json.Unmarshal(resp.Data, &version); err != nil || version.AppName == "" {
+			add(DiagnosticCheck{Name: "version", Detail: "unexpected version info " + string(resp.Data), Err: err})
+		} else {
+			add(DiagnosticCheck{Name: "version", OK: true, Detail: version.AppName + " " + version.AppVersion})
+		}
+	}
+
+	resp, err = bot.probe(ctx, "get_status")
+	if err != nil {
+		add(DiagnosticCheck{Name: "status", Detail: "failed to get status", Err: err})
+	} else {
+		var status struct {
+			Online bool `json:"online"`
+			Good   bool `json:"good"`
+		}
+		if err := json.Unmarshal(resp.Data, &status); err != nil {
+			add(DiagnosticCheck{Name: "status", Detail: "unexpected status " + string(resp.Data), Err: err})
+		} else {
+			add(DiagnosticCheck{
+				Name:   "status",
+				OK:     status.Online && status.Good,
+				Detail: fmt.Sprintf("online: %v, good: %v", status.Online, status.Good),
+			})
+		}
+	}
+
+	return report
+}
+
+// diagnoseHTTPAuth checks the access token, CQ HTTP responses 401 if the token
+// is missing and 403 if it is wrong.
+func (bot *BotAPI) diagnoseHTTPAuth(ctx context.Context) DiagnosticCheck {
+	c := DiagnosticCheck{Name: "auth"}
+	client := bot.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	method := fmt.Sprintf("%s/get_login_info?access_token=%s", bot.APIEndpoint, bot.Token)
+	req, err := http.NewRequest("POST", method, nil)
+	if err != nil {
+		c.Detail, c.Err = "invalid request", err
+		return c
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		c.Detail, c.Err = "endpoint unreachable", err
+		return c
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		c.Detail = "access token is required by CQ HTTP but not set"
+	case http.StatusForbidden:
+		c.Detail = "access token is wrong"
+	case http.StatusNotFound:
+		c.Detail = "api endpoint not found, check the address"
+	case http.StatusOK:
+		c.OK, c.Detail = true, "access token accepted"
+	default:
+		c.Detail = "unexpected response " + resp.Status
+	}
+	return c
+}
+
+// diagnoseWebSocket checks the connections of /api/ and /event/ websocket, or the universal websocket.
+func (bot *BotAPI) diagnoseWebSocket() DiagnosticCheck {
+	c := DiagnosticCheck{Name: "websocket"}
+	switch {
+	case bot.apiConn() == nil:
+		c.Detail = "api websocket not connected"
+	case bot.eventConn() == nil:
+		c.Detail = "event websocket not connected"
+	case bot.WSUniversal:
+		c.OK, c.Detail = true, "universal websocket connected"
+	default:
+		c.OK, c.Detail = true, "api and event websocket connected"
+	}
+	return c
+}
+
+// probe makes a request, which is aborted when ctx is done.
+func (bot *BotAPI) probe(ctx context.Context, endpoint string) (APIResponse, error) {
+	return bot.MakeRequestWithContext(ctx, endpoint, nil)
+}
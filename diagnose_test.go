@@ -0,0 +1,89 @@
+package qqbotapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newDiagnoseServer returns a server responding the probes of Diagnose, which requires token.
+func newDiagnoseServer(token string) *httptest.Server {
+	data := map[string]interface{}{
+		"get_login_info":   map[string]interface{}{"user_id": 10000, "nickname": "bot"},
+		"get_version_info": map[string]interface{}{"app_name": "go-cqhttp", "app_version": "v1.0.0"},
+		"get_status":       map[string]interface{}{"online": true, "good": true},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := json.Marshal(map[string]interface{}{
+			"status":  "ok",
+			"retcode": 0,
+			"data":    data[strings.TrimPrefix(r.URL.Path, "/")],
+		})
+		w.Write(b)
+	}))
+}
+
+func TestDiagnose(t *testing.T) {
+	server := newDiagnoseServer("token")
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL, Token: "token"}
+	report := bot.Diagnose(context.Background())
+	if report.OK() && len(report.Checks) == 5 && report.Checks[3].Detail == "go-cqhttp v1.0.0" {
+		t.Log("TestDiagnose passed")
+	} else {
+		t.Errorf("TestDiagnose failed: %v", report)
+	}
+}
+
+func TestDiagnose_Failed(t *testing.T) {
+	server := newDiagnoseServer("token")
+	defer server.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	for _, c := range []struct {
+		endpoint string
+		name     string
+		detail   string
+	}{
+		{server.URL, "auth", "access token is required by CQ HTTP but not set"},
+		{"ftp://localhost", "endpoint", "bad api url scheme ftp"},
+		{unreachable.URL, "auth", "endpoint unreachable"},
+	} {
+		bot := &BotAPI{Client: server.Client(), APIEndpoint: c.endpoint}
+		report := bot.Diagnose(context.Background())
+		var failed *DiagnosticCheck
+		for i := range report.Checks {
+			if !report.Checks[i].OK {
+				failed = &report.Checks[i]
+				break
+			}
+		}
+		if failed != nil && failed.Name == c.name && failed.Detail == c.detail {
+			t.Logf("TestDiagnose_Failed passed: %s", c.endpoint)
+		} else {
+			t.Errorf("TestDiagnose_Failed failed: %v", report)
+		}
+	}
+}
+
+func TestDiagnose_UnexpectedData(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	report := bot.Diagnose(context.Background())
+	if !report.OK() && !report.Checks[2].OK && !report.Checks[3].OK {
+		t.Log("TestDiagnose_UnexpectedData passed")
+	} else {
+		t.Errorf("TestDiagnose_UnexpectedData failed: %v", report)
+	}
+}
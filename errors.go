@@ -0,0 +1,107 @@
+package qqbotapi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PermissionError is returned when the bot is known to lack the permission
+// for an operation in a group, so that the request is not made at all.
+type PermissionError struct {
+	GroupID int64
+	Role    string // role of the bot in the group
+	Action  string
+}
+
+// Error implements the error interface.
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("not enough permission to %s in group %d (role: %s)", e.Action, e.GroupID, e.Role)
+}
+
+// ValidationError is returned by Send and Do when a config fails its Validate,
+// so that the request is not made at all.
+type ValidationError struct {
+	Method string // CQ HTTP API method name of the config
+	Field  string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+	}
+	return fmt.Sprintf("invalid %s for %s: %s", e.Field, e.Method, e.Reason)
+}
+
+// APIError is returned when CQ HTTP responds a status other than "ok".
+type APIError struct {
+	Status  string
+	RetCode int
+	Msg     string // error code provided by go-cqhttp, e.g. "NOT_MANAGEABLE"
+	Wording string // error description provided by go-cqhttp
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	s := e.Status + " " + strconv.Itoa(e.RetCode)
+	if e.Wording != "" {
+		s += ": " + e.Wording
+	} else if e.Msg != "" {
+		s += ": " + e.Msg
+	}
+	return s
+}
+
+// checkAPIResponse returns an *APIError if the status of resp is not "ok".
+func checkAPIResponse(resp APIResponse) error {
+	if resp.Status == "ok" {
+		return nil
+	}
+	return &APIError{
+		Status:  resp.Status,
+		RetCode: resp.RetCode,
+		Msg:     resp.Msg,
+		Wording: resp.Wording,
+	}
+}
+
+// IsAsync returns if err is an *APIError, which might be wrapped, meaning the request is accepted
+// and will be handled asynchronously, e.g. by an _async action.
+func IsAsync(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && (e.Status == "async" || e.RetCode == 1)
+}
+
+// permissionDeniedMsgs are the error codes of go-cqhttp meaning not enough permission.
+var permissionDeniedMsgs = []string{"NOT_MANAGEABLE", "PERMISSION_DENIED", "BOT_NOT_ADMIN"}
+
+// IsPermissionDenied returns if err means the bot lacks permission, either a *PermissionError
+// or an *APIError whose message says so, which might be wrapped.
+func IsPermissionDenied(err error) bool {
+	var permErr *PermissionError
+	if errors.As(err, &permErr) {
+		return true
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (containsString(permissionDeniedMsgs, apiErr.Msg) || strings.Contains(apiErr.Wording, "权限"))
+}
+
+// DecodeError is returned when the data of a response cannot be decoded,
+// e.g. if an implementation of CQ HTTP responds a different type.
+type DecodeError struct {
+	Endpoint string
+	Err      error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode the response of %s: %v", e.Endpoint, e.Err)
+}
+
+// Unwrap returns the error of encoding/json.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
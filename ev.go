@@ -2,49 +2,152 @@ package qqbotapi
 
 import (
 	"fmt"
+	"log"
 	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Events of request updates emitted by Ev.
+const (
+	EventFriendRequest    = "request.friend"
+	EventGroupJoinRequest = "request.group.add"
+	EventGroupInvite      = "request.group.invite"
+)
+
+// Events of notice updates emitted by Ev.
+const (
+	EventGroupRecall  = "notice.group_recall"
+	EventFriendRecall = "notice.friend_recall"
+	EventGroupCard    = "notice.group_card"
+	EventEssence      = "notice.essence"
+	EventClientStatus = "notice.client_status"
+	EventOfflineFile  = "notice.offline_file"
+)
+
+// Events of meta event updates emitted by Ev.
+const (
+	EventLifecycle = "meta_event.lifecycle"
+	EventHeartbeat = "meta_event.heartbeat"
 )
 
 type Ev struct {
 	updatesChannel UpdatesChannel
 	subscribers    map[string][]func(update Update)
+
+	// PanicHandler, if set, is called when a handler or a middleware panics, which is handled
+	// like other panics of Bot if set, or logged otherwise.
+	// The rest handlers of the update are skipped. It is set by EvPanicHandler.
+	PanicHandler PanicHandler
+	// Interceptor, if set, is called with every update and a function emitting its events,
+	// e.g. to start a trace span and pass it in the context of the update. It is set by EvInterceptor.
+	Interceptor func(update Update, emit func(update Update))
+	// Bot, if set, is passed to the middlewares in EvContext and to the typed handlers.
+	// It is set by EvBot.
+	Bot *BotAPI
+
+	middlewares []EvMiddleware
+	mux         sync.RWMutex // guards middlewares
+}
+
+// EvOption configures an Ev created by NewEv.
+//
+// The fields set by options must not be changed after NewEv,
+// since the updates are handled concurrently since then.
+type EvOption func(ev *Ev)
+
+// EvBot sets Ev.Bot.
+func EvBot(bot *BotAPI) EvOption {
+	return func(ev *Ev) {
+		ev.Bot = bot
+	}
+}
+
+// EvInterceptor sets Ev.Interceptor.
+func EvInterceptor(interceptor func(update Update, emit func(update Update))) EvOption {
+	return func(ev *Ev) {
+		ev.Interceptor = interceptor
+	}
 }
 
-func NewEv(channel UpdatesChannel) *Ev {
+// EvPanicHandler sets Ev.PanicHandler.
+func EvPanicHandler(handler PanicHandler) EvOption {
+	return func(ev *Ev) {
+		ev.PanicHandler = handler
+	}
+}
+
+// EvUse adds middlewares like Ev.Use, so that none of the updates misses them.
+func EvUse(middlewares ...EvMiddleware) EvOption {
+	return func(ev *Ev) {
+		ev.Use(middlewares...)
+	}
+}
+
+func NewEv(channel UpdatesChannel, options ...EvOption) *Ev {
 	ev := &Ev{
 		updatesChannel: channel,
 		subscribers:    make(map[string][]func(update Update)),
 	}
+	for _, option := range options {
+		option(ev)
+	}
 	go func() {
 		for update := range channel {
-			postType := update.PostType
-			var detailedType string
-			switch postType {
-			case "notice":
-				detailedType = update.NoticeType
-			case "message":
-				detailedType = update.MessageType
-			case "request":
-				detailedType = update.RequestType
-			}
-			if detailedType != "" {
-				if update.SubType != "" {
-					ev.Emit(
-						fmt.Sprintf("%s.%s.%s", postType, detailedType, update.SubType),
-						update,
-					)
-				}
-				ev.Emit(
-					fmt.Sprintf("%s.%s", postType, detailedType),
-					update,
-				)
+			if ev.Interceptor != nil {
+				ev.Interceptor(update, ev.handle)
+			} else {
+				ev.handle(update)
 			}
-			ev.Emit(postType, update)
 		}
 	}()
 	return ev
 }
 
+// recoverPanic recovers from panics of the middlewares and the handlers, which are passed
+// to ev.PanicHandler if set, or handled like other panics of ev.Bot if set, or logged otherwise.
+func (ev *Ev) recoverPanic() {
+	if r := recover(); r != nil {
+		if ev.PanicHandler != nil {
+			ev.PanicHandler(r, debug.Stack())
+		} else if ev.Bot != nil {
+			ev.Bot.handlePanic("Ev handler", r)
+		} else {
+			log.Printf("Recovered from panic in Ev handler: %v\n%s", r, debug.Stack())
+		}
+	}
+}
+
+// dispatch emits the events of an update.
+func (ev *Ev) dispatch(update Update) {
+	postType := update.PostType
+	var detailedType string
+	switch postType {
+	case "notice":
+		detailedType = update.NoticeType
+	case "message":
+		detailedType = update.MessageType
+	case "request":
+		detailedType = update.RequestType
+	case "meta_event":
+		detailedType = update.MetaEventType
+	}
+	if detailedType != "" {
+		if update.SubType != "" {
+			ev.Emit(
+				fmt.Sprintf("%s.%s.%s", postType, detailedType, update.SubType),
+				update,
+			)
+		}
+		ev.Emit(
+			fmt.Sprintf("%s.%s", postType, detailedType),
+			update,
+		)
+	}
+	ev.Emit(postType, update)
+}
+
 type Unsubscribe func()
 
 func (ev *Ev) Emit(event string, update Update) {
@@ -84,3 +187,33 @@ func (ev *Ev) Off(event string) func(func(update Update)) {
 		ev.subscribers[event] = newHandlers
 	}
 }
+
+// WatchHeartbeat calls onMissed with the last heartbeat if the next one does not arrive
+// in twice its interval, e.g. when the implementation is down. It is called again only after
+// heartbeats arrive again. Nothing is watched until the first heartbeat arrives.
+func (ev *Ev) WatchHeartbeat(onMissed func(last Heartbeat)) Unsubscribe {
+	var mux sync.Mutex
+	var timer *time.Timer
+	unsubscribe := ev.On(EventHeartbeat)(func(update Update) {
+		heartbeat := update.Heartbeat()
+		if heartbeat == nil || heartbeat.Interval <= 0 {
+			return
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(2*heartbeat.Interval, func() {
+			onMissed(*heartbeat)
+		})
+	})
+	return func() {
+		unsubscribe()
+		mux.Lock()
+		defer mux.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
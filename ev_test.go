@@ -0,0 +1,79 @@
+package qqbotapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEvMetaEvents(t *testing.T) {
+	ev := &Ev{subscribers: make(map[string][]func(update Update))}
+
+	var lifecycle *Lifecycle
+	ev.On("meta_event.lifecycle.connect")(func(update Update) {
+		lifecycle = update.Lifecycle()
+	})
+	missed := make(chan Heartbeat, 1)
+	defer ev.WatchHeartbeat(func(last Heartbeat) {
+		missed <- last
+	})()
+
+	for _, data := range []string{
+		`{"post_type":"meta_event","meta_event_type":"lifecycle","sub_type":"connect","time":1}`,
+		`{"post_type":"meta_event","meta_event_type":"heartbeat","status":{"online":true,"good":true},"interval":10,"time":2}`,
+	} {
+		var update Update
+		if err := json.Unmarshal([]byte(data), &update); err != nil {
+			t.Fatalf("TestEvMetaEvents failed: %v", err)
+		}
+		ev.handle(update)
+	}
+
+	select {
+	case last := <-missed:
+		if lifecycle != nil && lifecycle.SubType == "connect" && last.Status.Online && last.Interval == 10*time.Millisecond {
+			t.Log("TestEvMetaEvents passed")
+		} else {
+			t.Errorf("TestEvMetaEvents failed: %v %v", lifecycle, last)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("TestEvMetaEvents failed: missed heartbeat not detected")
+	}
+}
+
+func TestEvTypedHandlers(t *testing.T) {
+	bot := &BotAPI{}
+	ev := &Ev{subscribers: make(map[string][]func(update Update)), Bot: bot}
+
+	var texts []string
+	var recall *GroupRecall
+	var request *FriendRequest
+	ev.OnGroupMessage(func(b *BotAPI, msg *Message) {
+		if b == bot {
+			texts = append(texts, msg.Text)
+		}
+	})
+	ev.OnGroupRecall(func(b *BotAPI, r *GroupRecall) {
+		recall = r
+	})
+	unsubscribe := ev.OnFriendRequest(func(b *BotAPI, r *FriendRequest) {
+		request = r
+	})
+	unsubscribe()
+
+	for _, update := range []Update{
+		{PostType: "message", MessageType: "group", GroupID: 10000, RawMessage: "hi"},
+		{PostType: "message", MessageType: "private", UserID: 10000, RawMessage: "private"},
+		{PostType: "notice", NoticeType: "group_recall", GroupID: 10000, UserID: 100000, OperatorID: 100001, MessageID: 12},
+		{PostType: "request", RequestType: "friend", UserID: 10000, Flag: "flag"},
+	} {
+		update.ParseRawMessage()
+		ev.handle(update)
+	}
+
+	if len(texts) == 1 && texts[0] == "hi" && recall != nil && recall.MessageID == 12 && recall.OperatorID == 100001 && request == nil {
+		t.Log("TestEvTypedHandlers passed")
+	} else {
+		t.Errorf("TestEvTypedHandlers failed: %v %v %v", texts, recall, request)
+	}
+}
@@ -0,0 +1,126 @@
+package qqbotapi
+
+// Typed subscriptions of Ev, whose handlers are called with Ev.Bot, which is nil if not set,
+// and the event of the update.
+
+// OnPrivateMessage subscribes to private messages.
+func (ev *Ev) OnPrivateMessage(handler func(bot *BotAPI, msg *Message)) Unsubscribe {
+	return ev.onMessage("message.private", handler)
+}
+
+// OnGroupMessage subscribes to group messages.
+func (ev *Ev) OnGroupMessage(handler func(bot *BotAPI, msg *Message)) Unsubscribe {
+	return ev.onMessage("message.group", handler)
+}
+
+// OnGuildMessage subscribes to guild messages.
+func (ev *Ev) OnGuildMessage(handler func(bot *BotAPI, msg *Message)) Unsubscribe {
+	return ev.onMessage("message."+ChatTypeGuild, handler)
+}
+
+func (ev *Ev) onMessage(event string, handler func(bot *BotAPI, msg *Message)) Unsubscribe {
+	return ev.On(event)(func(update Update) {
+		if update.Message != nil {
+			handler(ev.Bot, update.Message)
+		}
+	})
+}
+
+// OnFriendRequest subscribes to friend requests.
+func (ev *Ev) OnFriendRequest(handler func(bot *BotAPI, req *FriendRequest)) Unsubscribe {
+	return ev.On(EventFriendRequest)(func(update Update) {
+		if req := update.FriendRequest(); req != nil {
+			handler(ev.Bot, req)
+		}
+	})
+}
+
+// OnGroupJoinRequest subscribes to requests of users to join the groups managed by the bot.
+func (ev *Ev) OnGroupJoinRequest(handler func(bot *BotAPI, req *GroupJoinRequest)) Unsubscribe {
+	return ev.On(EventGroupJoinRequest)(func(update Update) {
+		if req := update.GroupJoinRequest(); req != nil {
+			handler(ev.Bot, req)
+		}
+	})
+}
+
+// OnGroupInvite subscribes to invitations for the bot to join groups.
+func (ev *Ev) OnGroupInvite(handler func(bot *BotAPI, invite *GroupInvite)) Unsubscribe {
+	return ev.On(EventGroupInvite)(func(update Update) {
+		if invite := update.GroupInvite(); invite != nil {
+			handler(ev.Bot, invite)
+		}
+	})
+}
+
+// OnGroupRecall subscribes to messages recalled in groups.
+func (ev *Ev) OnGroupRecall(handler func(bot *BotAPI, recall *GroupRecall)) Unsubscribe {
+	return ev.On(EventGroupRecall)(func(update Update) {
+		if recall := update.GroupRecall(); recall != nil {
+			handler(ev.Bot, recall)
+		}
+	})
+}
+
+// OnFriendRecall subscribes to messages recalled by friends.
+func (ev *Ev) OnFriendRecall(handler func(bot *BotAPI, recall *FriendRecall)) Unsubscribe {
+	return ev.On(EventFriendRecall)(func(update Update) {
+		if recall := update.FriendRecall(); recall != nil {
+			handler(ev.Bot, recall)
+		}
+	})
+}
+
+// OnGroupCardChange subscribes to changes of the cards of group members.
+func (ev *Ev) OnGroupCardChange(handler func(bot *BotAPI, change *GroupCardChange)) Unsubscribe {
+	return ev.On(EventGroupCard)(func(update Update) {
+		if change := update.GroupCardChange(); change != nil {
+			handler(ev.Bot, change)
+		}
+	})
+}
+
+// OnEssenceChange subscribes to changes of the essence messages of groups.
+func (ev *Ev) OnEssenceChange(handler func(bot *BotAPI, change *EssenceChange)) Unsubscribe {
+	return ev.On(EventEssence)(func(update Update) {
+		if change := update.EssenceChange(); change != nil {
+			handler(ev.Bot, change)
+		}
+	})
+}
+
+// OnClientStatus subscribes to changes of the online status of other devices of the bot.
+func (ev *Ev) OnClientStatus(handler func(bot *BotAPI, status *ClientStatus)) Unsubscribe {
+	return ev.On(EventClientStatus)(func(update Update) {
+		if status := update.ClientStatus(); status != nil {
+			handler(ev.Bot, status)
+		}
+	})
+}
+
+// OnOfflineFile subscribes to files sent by friends.
+func (ev *Ev) OnOfflineFile(handler func(bot *BotAPI, file *OfflineFile)) Unsubscribe {
+	return ev.On(EventOfflineFile)(func(update Update) {
+		if file := update.OfflineFile(); file != nil {
+			handler(ev.Bot, file)
+		}
+	})
+}
+
+// OnLifecycle subscribes to lifecycle meta events.
+func (ev *Ev) OnLifecycle(handler func(bot *BotAPI, lifecycle *Lifecycle)) Unsubscribe {
+	return ev.On(EventLifecycle)(func(update Update) {
+		if lifecycle := update.Lifecycle(); lifecycle != nil {
+			handler(ev.Bot, lifecycle)
+		}
+	})
+}
+
+// OnHeartbeat subscribes to heartbeat meta events.
+func (ev *Ev) OnHeartbeat(handler func(bot *BotAPI, heartbeat *Heartbeat)) Unsubscribe {
+	return ev.On(EventHeartbeat)(func(update Update) {
+		if heartbeat := update.Heartbeat(); heartbeat != nil {
+			handler(ev.Bot, heartbeat)
+		}
+	})
+}
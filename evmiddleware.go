@@ -0,0 +1,127 @@
+package qqbotapi
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// EvContext is passed through the middlewares of Ev for an update.
+// Handlers get it with EvContextOf.
+type EvContext struct {
+	Bot    *BotAPI // Ev.Bot, which might be nil
+	Update Update
+
+	mux    sync.Mutex
+	values map[string]interface{}
+}
+
+// Set saves a value for the update, e.g. the user authenticated by a middleware.
+func (c *EvContext) Set(key string, value interface{}) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+// Get returns the value saved with Set.
+func (c *EvContext) Get(key string) (interface{}, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// evContextKey is the key of EvContext in the context of an update.
+type evContextKey struct{}
+
+// EvContextOf returns the EvContext of an update emitted by Ev,
+// or nil if the Ev has no middlewares.
+func EvContextOf(update Update) *EvContext {
+	c, _ := update.Context().Value(evContextKey{}).(*EvContext)
+	return c
+}
+
+// EvMiddleware intercepts the updates of Ev before their events are emitted,
+// e.g. to authenticate or to throttle, and skips the handlers by not calling next.
+type EvMiddleware func(c *EvContext, next func(c *EvContext))
+
+// Use adds middlewares, the first of which is the outermost. They run after Interceptor.
+//
+// The updates received before are not passed through them, unless they are added by EvUse.
+func (ev *Ev) Use(middlewares ...EvMiddleware) {
+	ev.mux.Lock()
+	defer ev.mux.Unlock()
+	ev.middlewares = append(ev.middlewares[:len(ev.middlewares):len(ev.middlewares)], middlewares...)
+}
+
+// handle passes an update through the middlewares, and emits its events,
+// recovering from panics of both.
+func (ev *Ev) handle(update Update) {
+	defer ev.recoverPanic()
+	ev.mux.RLock()
+	middlewares := ev.middlewares
+	ev.mux.RUnlock()
+	if len(middlewares) == 0 {
+		ev.dispatch(update)
+		return
+	}
+	handler := func(c *EvContext) {
+		ev.dispatch(c.Update.WithContext(context.WithValue(c.Update.Context(), evContextKey{}, c)))
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, next := middlewares[i], handler
+		handler = func(c *EvContext) {
+			middleware(c, next)
+		}
+	}
+	handler(&EvContext{Bot: ev.Bot, Update: update})
+}
+
+// Recover returns a middleware recovering from panics of the later middlewares and the handlers,
+// which are handled like other panics of Ev.Bot if set, or logged otherwise.
+//
+// Panics are recovered by Ev anyway, which passes them to Ev.PanicHandler instead.
+func Recover() EvMiddleware {
+	return func(c *EvContext, next func(c *EvContext)) {
+		defer func() {
+			if r := recover(); r != nil {
+				if c.Bot != nil {
+					c.Bot.handlePanic("Ev middleware", r)
+				} else {
+					log.Printf("Recovered from panic in Ev middleware: %v\n%s", r, debug.Stack())
+				}
+			}
+		}()
+		next(c)
+	}
+}
+
+// AdminOnly returns a middleware passing only the updates from superusers,
+// or from the owners and admins of the group if the update is a group message.
+func AdminOnly(superusers ...int64) EvMiddleware {
+	return func(c *EvContext, next func(c *EvContext)) {
+		for _, id := range superusers {
+			if c.Update.UserID == id {
+				next(c)
+				return
+			}
+		}
+		sender := c.Update.Sender
+		if c.Update.MessageType == "group" && sender != nil && (sender.Role == "owner" || sender.Role == "admin") {
+			next(c)
+		}
+	}
+}
+
+// Cooldown returns a middleware passing at most one update from a user in every period d,
+// and skipping the others. Updates without UserID are always passed.
+//
+// Use Throttle for other keys, responses when throttled, or a shared store.
+func Cooldown(d time.Duration) EvMiddleware {
+	return (&Throttle{Duration: d}).Middleware()
+}
@@ -0,0 +1,97 @@
+package qqbotapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvMiddlewares(t *testing.T) {
+	ev := &Ev{subscribers: make(map[string][]func(update Update))}
+	ev.Use(Recover(), AdminOnly(10000), Cooldown(5*time.Second), func(c *EvContext, next func(c *EvContext)) {
+		if c.Update.UserID == 40000 {
+			panic("panic in middleware")
+		}
+		c.Set("user", c.Update.UserID)
+		next(c)
+	})
+
+	var handled []interface{}
+	ev.On("message")(func(update Update) {
+		user, _ := EvContextOf(update).Get("user")
+		handled = append(handled, user)
+	})
+
+	now := time.Unix(0, 0)
+	timeNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	admin := &User{Role: "admin"}
+	for _, update := range []Update{
+		{PostType: "message", MessageType: "private", UserID: 10000},
+		{PostType: "message", MessageType: "private", UserID: 10000},
+		{PostType: "message", MessageType: "group", UserID: 20000, Sender: admin},
+		{PostType: "message", MessageType: "group", UserID: 30000, Sender: &User{Role: "member"}},
+		{PostType: "message", MessageType: "group", UserID: 40000, Sender: admin},
+	} {
+		ev.handle(update)
+	}
+	now = now.Add(5 * time.Second)
+	ev.handle(Update{PostType: "message", MessageType: "private", UserID: 10000})
+
+	if reflect.DeepEqual(handled, []interface{}{int64(10000), int64(20000), int64(10000)}) {
+		t.Log("TestEvMiddlewares passed")
+	} else {
+		t.Errorf("TestEvMiddlewares failed: %v", handled)
+	}
+}
+
+func TestNewEvOptions(t *testing.T) {
+	bot := &BotAPI{}
+	updates := make(chan Update)
+	recovered := make(chan interface{}, 1)
+	ev := NewEv(updates,
+		EvBot(bot),
+		EvPanicHandler(func(r interface{}, stack []byte) { recovered <- r }),
+		EvUse(func(c *EvContext, next func(c *EvContext)) {
+			if c.Update.UserID == 40000 {
+				panic("panic in middleware")
+			}
+			next(c)
+		}),
+	)
+
+	handled := make(chan *BotAPI, 1)
+	ev.On("message")(func(update Update) {
+		handled <- EvContextOf(update).Bot
+	})
+	updates <- Update{PostType: "message", UserID: 40000}
+	updates <- Update{PostType: "message", UserID: 10000}
+	close(updates)
+
+	if r, b := <-recovered, <-handled; r == "panic in middleware" && b == bot {
+		t.Log("TestNewEvOptions passed")
+	} else {
+		t.Errorf("TestNewEvOptions failed: %v %v", r, b)
+	}
+}
+
+func TestEv_BotPanicHandler(t *testing.T) {
+	var recovered interface{}
+	bot := &BotAPI{PanicHandler: func(r interface{}, stack []byte) { recovered = r }}
+	ev := &Ev{subscribers: make(map[string][]func(update Update)), Bot: bot}
+	ev.On("message")(func(update Update) {
+		panic("panic in handler")
+	})
+	ev.handle(Update{PostType: "message"})
+
+	if recovered == "panic in handler" {
+		t.Log("TestEv_BotPanicHandler passed")
+	} else {
+		t.Errorf("TestEv_BotPanicHandler failed: %v", recovered)
+	}
+}
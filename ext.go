@@ -0,0 +1,132 @@
+package qqbotapi
+
+import (
+	"errors"
+	"sync"
+)
+
+// ExtendedImplementations are the app names, reported by get_version_info,
+// of implementations known to support the actions wrapped by Extensions.
+//
+// Add the app name of your fork if it supports them too.
+var ExtendedImplementations = []string{"NapCat.Onebot", "LLOneBot", "Lagrange.OneBot"}
+
+// ErrExtensionUnsupported is returned by Extensions if the implementation
+// does not support extended actions.
+var ErrExtensionUnsupported = errors.New("extended actions are not supported by the implementation")
+
+// Extensions wraps extended actions provided by NTQQ based implementations,
+// e.g. NapCat and LLOneBot.
+type Extensions struct {
+	bot *BotAPI
+
+	mux      sync.Mutex
+	detected bool
+	appName  string
+}
+
+// Ext returns the extensions of the bot.
+func (bot *BotAPI) Ext() *Extensions {
+	bot.extOnce.Do(func() {
+		bot.ext = &Extensions{bot: bot}
+	})
+	return bot.ext
+}
+
+// AppName returns the app name of the implementation, detected with get_version_info.
+func (ext *Extensions) AppName() (string, error) {
+	ext.mux.Lock()
+	defer ext.mux.Unlock()
+	if ext.detected {
+		return ext.appName, nil
+	}
+	var version struct {
+		AppName string `json:"app_name"`
+	}
+	if err := ext.bot.DoInto(GenericConfig{Action: "get_version_info"}, &version); err != nil {
+		return "", err
+	}
+	ext.detected = true
+	ext.appName = version.AppName
+	return ext.appName, nil
+}
+
+// Supported returns if the implementation supports extended actions.
+//
+// Detection is done once, and retried only if it fails.
+func (ext *Extensions) Supported() (bool, error) {
+	appName, err := ext.AppName()
+	if err != nil {
+		return false, err
+	}
+	return containsString(ExtendedImplementations, appName), nil
+}
+
+// check returns ErrExtensionUnsupported if extended actions are not supported.
+func (ext *Extensions) check() error {
+	supported, err := ext.Supported()
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return ErrExtensionUnsupported
+	}
+	return nil
+}
+
+// do sends an extended action if supported.
+func (ext *Extensions) do(action string, params map[string]interface{}, result interface{}) error {
+	if err := ext.check(); err != nil {
+		return err
+	}
+	return ext.bot.DoInto(GenericConfig{Action: action, Params: params}, result)
+}
+
+// SetMessageEmojiLike reacts to a message with an emoji, or cancels the reaction.
+func (ext *Extensions) SetMessageEmojiLike(messageID int64, emojiID string, set bool) error {
+	return ext.do("set_msg_emoji_like", map[string]interface{}{
+		"message_id": messageID,
+		"emoji_id":   emojiID,
+		"set":        set,
+	}, nil)
+}
+
+// SetInputStatus shows the bot is typing (1) or speaking (0) in a private chat.
+func (ext *Extensions) SetInputStatus(userID int64, eventType int) error {
+	return ext.do("set_input_status", map[string]interface{}{
+		"user_id":    userID,
+		"event_type": eventType,
+	}, nil)
+}
+
+// GetFriendMessageHistory fetches at most count messages in a private chat,
+// before the message of messageSeq, or the latest if messageSeq is 0.
+func (ext *Extensions) GetFriendMessageHistory(userID int64, messageSeq int64, count int) ([]Message, error) {
+	params := map[string]interface{}{
+		"user_id": userID,
+		"count":   count,
+	}
+	if messageSeq != 0 {
+		params["message_seq"] = messageSeq
+	}
+	if err := ext.check(); err != nil {
+		return nil, err
+	}
+	return ext.bot.messageHistory("get_friend_msg_history", params)
+}
+
+// GetGroupMessageHistory fetches at most count messages in a group,
+// before the message of messageSeq, or the latest if messageSeq is 0.
+func (ext *Extensions) GetGroupMessageHistory(groupID int64, messageSeq int64, count int) ([]Message, error) {
+	params := map[string]interface{}{
+		"group_id": groupID,
+		"count":    count,
+	}
+	if messageSeq != 0 {
+		params["message_seq"] = messageSeq
+	}
+	if err := ext.check(); err != nil {
+		return nil, err
+	}
+	return ext.bot.messageHistory("get_group_msg_history", params)
+}
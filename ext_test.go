@@ -0,0 +1,32 @@
+package qqbotapi
+
+import (
+	"testing"
+)
+
+func TestExtensions(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"app_name": "NapCat.Onebot",
+		"messages": []map[string]interface{}{
+			{"message_id": 1, "message_type": "group", "group_id": 10000, "sender": map[string]interface{}{"user_id": 100000}, "message": "hi[CQ:face,id=14]"},
+			{"message_id": 2, "message_type": "group", "group_id": 10000, "sender": map[string]interface{}{"user_id": 100001}, "message": "hello"},
+		},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	messages, err := bot.Ext().GetGroupMessageHistory(10000, 0, 20)
+	if err == nil && len(messages) == 2 && messages[0].Text == "hi[CQ:face,id=14]" && messages[1].From.ID == 100001 && messages[1].Chat.ID == 10000 {
+		t.Log("TestExtensions passed")
+	} else {
+		t.Errorf("TestExtensions failed: %v %v", err, messages)
+	}
+
+	ExtendedImplementations = ExtendedImplementations[1:]
+	defer func() {
+		ExtendedImplementations = append([]string{"NapCat.Onebot"}, ExtendedImplementations...)
+	}()
+	if err := bot.Ext().SetInputStatus(100000, 1); err != ErrExtensionUnsupported {
+		t.Errorf("TestExtensions failed: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package qqbotapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// FailoverConfig contains the API endpoints over HTTP switched among when one is down.
+type FailoverConfig struct {
+	// Endpoints are in order of preference, e.g. bot.APIEndpoint followed by standbys.
+	// The most preferred healthy endpoint is used.
+	Endpoints []string
+	// Interval is the interval of health checks, which is 30 seconds if not set.
+	Interval time.Duration
+	// Timeout is the timeout of each health check, which is 5 seconds if not set.
+	Timeout time.Duration
+}
+
+// failover keeps the health of the endpoints.
+type failover struct {
+	endpoints []string
+	healthy   []bool
+	current   int
+	mux       sync.Mutex
+	stop      context.CancelFunc // stops the health checks
+	done      chan struct{}      // closed when the health checks stop
+}
+
+// EnableFailover switches the API endpoint of a bot over HTTP to the next healthy one
+// in config.Endpoints when a request cannot connect or a health check fails,
+// and back to a preferred one when it recovers. Health checks run until ctx is done.
+//
+// Calling it again replaces the endpoints, and stops the health checks of the former ones.
+//
+// Requests which fail to connect are made again to the next endpoint,
+// while the other failed requests are not, since they might have been handled.
+func (bot *BotAPI) EnableFailover(ctx context.Context, config FailoverConfig) error {
+	if bot.Client == nil {
+		return errors.New("failover is only supported over HTTP")
+	}
+	if len(config.Endpoints) == 0 {
+		return &ValidationError{Field: "Endpoints", Reason: "required"}
+	}
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	ctx, stop := context.WithCancel(ctx)
+	f := &failover{
+		endpoints: config.Endpoints,
+		healthy:   make([]bool, len(config.Endpoints)),
+		stop:      stop,
+		done:      make(chan struct{}),
+	}
+	for i := range f.healthy {
+		f.healthy[i] = true
+	}
+	bot.failoverMux.Lock()
+	if bot.failover != nil {
+		bot.failover.stop()
+	}
+	bot.failover = f
+	bot.failoverMux.Unlock()
+
+	go func() {
+		defer close(f.done)
+		defer bot.recoverPanic("EnableFailover")
+		defer stop()
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				bot.checkEndpoints(ctx, f, config.Timeout)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// ActiveEndpoint returns the API endpoint requests are made to.
+func (bot *BotAPI) ActiveEndpoint() string {
+	f := bot.failoverState()
+	if f == nil {
+		return bot.APIEndpoint
+	}
+	return f.active()
+}
+
+// failoverState returns the failover enabled by EnableFailover, or nil if it is not enabled.
+func (bot *BotAPI) failoverState() *failover {
+	bot.failoverMux.Lock()
+	defer bot.failoverMux.Unlock()
+	return bot.failover
+}
+
+// checkEndpoints checks every endpoint of f with get_status, and switches to
+// the most preferred healthy one.
+func (bot *BotAPI) checkEndpoints(ctx context.Context, f *failover, timeout time.Duration) {
+	for i, endpoint := range f.endpoints {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		body, err := bot.openHTTPRequestTo(checkCtx, endpoint, "get_status", formBody(nil))
+		if err == nil {
+			body.Close()
+		}
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		f.mux.Lock()
+		f.healthy[i] = err == nil
+		f.mux.Unlock()
+	}
+
+	from := f.active()
+	if to := f.pick(); to != from {
+		bot.logger().Warn("Switching API endpoint", "from", from, "to", to)
+	}
+}
+
+// active returns the current endpoint.
+func (f *failover) active() string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.endpoints[f.current]
+}
+
+// markDown marks endpoint unhealthy and returns the endpoint switched to.
+func (f *failover) markDown(endpoint string) string {
+	f.mux.Lock()
+	for i, e := range f.endpoints {
+		if e == endpoint {
+			f.healthy[i] = false
+		}
+	}
+	f.mux.Unlock()
+	return f.pick()
+}
+
+// pick switches to the most preferred healthy endpoint, or keeps the current one
+// if none is healthy, and returns it.
+func (f *failover) pick() string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	for i, healthy := range f.healthy {
+		if healthy {
+			f.current = i
+			break
+		}
+	}
+	return f.endpoints[f.current]
+}
+
+// isDialError reports whether err means the connection is not established,
+// so that the request has not been sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
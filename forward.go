@@ -0,0 +1,132 @@
+package qqbotapi
+
+import (
+	"encoding/json"
+	"github.com/catsworld/qq-bot-api/cqcode"
+	"net/url"
+)
+
+// ForwardMessage is a merged forward message.
+type ForwardMessage struct {
+	ID    string
+	Nodes []ForwardNode
+}
+
+// ForwardNode is a message in a merged forward message.
+//
+// When sending, it is either a custom node of UserID, Nickname and Message,
+// or a node referring to an existing message of MessageID, see NewForwardNode and NewForwardNodeRef.
+type ForwardNode struct {
+	UserID    int64
+	Nickname  string
+	Time      int64
+	Message   cqcode.Message
+	MessageID int64 // only for sending
+	// Forwards are the forward messages nested in Message, whose Nodes are empty
+	// unless their content is provided inline, which could be fetched with GetForwardMessage then.
+	Forwards []ForwardMessage
+}
+
+// forwardNodeData is a node in the response of get_forward_msg, which is in the format of
+// go-cqhttp with "content", or NapCat and LLOneBot with "message".
+type forwardNodeData struct {
+	Content interface{} `json:"content"`
+	Message interface{} `json:"message"`
+	Sender  struct {
+		UserID   int64  `json:"user_id"`
+		Nickname string `json:"nickname"`
+	} `json:"sender"`
+	UserID int64 `json:"user_id"`
+	Time   int64 `json:"time"`
+}
+
+// node parses forwardNodeData to a ForwardNode.
+func (d forwardNodeData) node() ForwardNode {
+	raw := d.Content
+	if raw == nil {
+		raw = d.Message
+	}
+	message, _ := cqcode.ParseMessage(raw)
+	node := ForwardNode{
+		UserID:   d.Sender.UserID,
+		Nickname: d.Sender.Nickname,
+		Time:     d.Time,
+		Message:  message,
+	}
+	if node.UserID == 0 {
+		node.UserID = d.UserID
+	}
+	for _, media := range message {
+		f, ok := media.(*cqcode.Forward)
+		if !ok {
+			continue
+		}
+		forward := ForwardMessage{ID: f.ID}
+		if content, ok := f.Content.([]interface{}); ok {
+			forward.Nodes = parseForwardNodes(content)
+		}
+		node.Forwards = append(node.Forwards, forward)
+	}
+	return node
+}
+
+// segment returns the node segment of n sent in a forward message.
+func (n ForwardNode) segment() map[string]interface{} {
+	data := map[string]interface{}{}
+	if n.MessageID != 0 {
+		data["id"] = n.MessageID
+	} else {
+		data["name"] = n.Nickname
+		data["uin"] = n.UserID
+		data["content"] = n.Message.CQString()
+	}
+	return map[string]interface{}{
+		"type": "node",
+		"data": data,
+	}
+}
+
+// parseForwardNodes parses the nodes of a forward message provided inline.
+func parseForwardNodes(content []interface{}) []ForwardNode {
+	b, err := json.Marshal(content)
+	if err != nil {
+		return nil
+	}
+	var data []forwardNodeData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil
+	}
+	nodes := make([]ForwardNode, 0, len(data))
+	for _, d := range data {
+		nodes = append(nodes, d.node())
+	}
+	return nodes
+}
+
+// GetForwardMessage fetches a merged forward message by its ID, e.g. of a forward segment.
+func (bot *BotAPI) GetForwardMessage(id string) (ForwardMessage, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	v.Add("message_id", id) // NapCat and LLOneBot accept message_id only
+	resp, err := bot.MakeRequest("get_forward_msg", v)
+	if err != nil {
+		return ForwardMessage{}, err
+	}
+	var data struct {
+		Messages []forwardNodeData `json:"messages"`
+	}
+	if err := decodeData("get_forward_msg", resp.Data, &data); err != nil {
+		return ForwardMessage{}, err
+	}
+	forward := ForwardMessage{
+		ID:    id,
+		Nodes: make([]ForwardNode, 0, len(data.Messages)),
+	}
+	for _, d := range data.Messages {
+		forward.Nodes = append(forward.Nodes, d.node())
+	}
+
+	bot.debugLog("GetForwardMessage", nil, forward)
+
+	return forward, nil
+}
@@ -0,0 +1,171 @@
+package qqbotapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// ChatTypeGuild is the message type of guild messages, and the Chat.Type of them.
+const ChatTypeGuild = "guild"
+
+// Guild is a guild (频道) joined by the bot, from GetGuildList.
+type Guild struct {
+	GuildID        string `json:"guild_id"`
+	GuildName      string `json:"guild_name"`
+	GuildDisplayID string `json:"guild_display_id"`
+}
+
+// GuildChannel is a channel (子频道) of a guild, from GetGuildChannelList.
+type GuildChannel struct {
+	OwnerGuildID  string `json:"owner_guild_id"`
+	ChannelID     string `json:"channel_id"`
+	ChannelType   int    `json:"channel_type"` // 1 text, 2 voice, 5 live, 7 topic
+	ChannelName   string `json:"channel_name"`
+	CreateTime    int64  `json:"create_time"`
+	CreatorTinyID string `json:"creator_tiny_id"`
+}
+
+// GuildMember is a member of a guild.
+type GuildMember struct {
+	TinyID   string `json:"tiny_id"`
+	Title    string `json:"title"`
+	Nickname string `json:"nickname"`
+	RoleID   string `json:"role_id"`
+	RoleName string `json:"role_name"`
+}
+
+// GuildMemberList is a page of the members of a guild, from GetGuildMemberList.
+type GuildMemberList struct {
+	Members   []GuildMember `json:"members"`
+	Finished  bool          `json:"finished"`
+	NextToken string        `json:"next_token"` // to fetch the next page
+}
+
+// GetGuildList fetches the guilds joined by the bot, which is a go-cqhttp extension.
+func (bot *BotAPI) GetGuildList() ([]Guild, error) {
+	var guilds []Guild
+	err := bot.DoInto(GenericConfig{Action: "get_guild_list"}, &guilds)
+	if err != nil {
+		return nil, err
+	}
+	bot.debugLog("GetGuildList", nil, guilds)
+	return guilds, nil
+}
+
+// GetGuildChannelList fetches the channels of a guild, which is a go-cqhttp extension.
+func (bot *BotAPI) GetGuildChannelList(guildID string, noCache bool) ([]GuildChannel, error) {
+	var channels []GuildChannel
+	err := bot.DoInto(GenericConfig{Action: "get_guild_channel_list", Params: map[string]interface{}{
+		"guild_id": guildID,
+		"no_cache": noCache,
+	}}, &channels)
+	if err != nil {
+		return nil, err
+	}
+	bot.debugLog("GetGuildChannelList", nil, channels)
+	return channels, nil
+}
+
+// GetGuildMemberList fetches a page of the members of a guild, which is a go-cqhttp extension.
+//
+// nextToken is the NextToken of the last page, or "" for the first page.
+func (bot *BotAPI) GetGuildMemberList(guildID string, nextToken string) (GuildMemberList, error) {
+	params := map[string]interface{}{
+		"guild_id": guildID,
+	}
+	if nextToken != "" {
+		params["next_token"] = nextToken
+	}
+	var list GuildMemberList
+	err := bot.DoInto(GenericConfig{Action: "get_guild_member_list", Params: params}, &list)
+	if err != nil {
+		return list, err
+	}
+	bot.debugLog("GetGuildMemberList", nil, list)
+	return list, nil
+}
+
+// SendGuildChannelMessage sends message to a channel of a guild, and returns the message ID,
+// which is a go-cqhttp extension.
+//
+// message is formatted like NewMessage.
+func (bot *BotAPI) SendGuildChannelMessage(guildID string, channelID string, message interface{}) (string, error) {
+	var data struct {
+		MessageID string `json:"message_id"`
+	}
+	err := bot.DoInto(GuildChannelMessageConfig{
+		GuildID:   guildID,
+		ChannelID: channelID,
+		Text:      NewMessage(0, ChatTypeGuild, message).Text,
+	}, &data)
+	if err != nil {
+		return "", err
+	}
+	return data.MessageID, nil
+}
+
+// stringID is an ID decoded from either a string or a number.
+type stringID string
+
+func (id *stringID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	*id = stringID(bytes.Trim(data, `"`))
+	return nil
+}
+
+// UnmarshalJSON decodes an update. In guild messages, the message ID is a string
+// kept in GuildMessageID, and the IDs of the user and the sender are tiny IDs, which are strings.
+func (update *Update) UnmarshalJSON(data []byte) error {
+	type plainUpdate Update
+	var raw struct {
+		*plainUpdate
+		MessageID json.RawMessage `json:"message_id"`
+		UserID    json.RawMessage `json:"user_id"`
+		Sender    json.RawMessage `json:"sender"`
+	}
+	raw.plainUpdate = (*plainUpdate)(update)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if update.MessageType != ChatTypeGuild {
+		if err := unmarshalRaw(raw.MessageID, &update.MessageID); err != nil {
+			return err
+		}
+		if err := unmarshalRaw(raw.UserID, &update.UserID); err != nil {
+			return err
+		}
+		return unmarshalRaw(raw.Sender, &update.Sender)
+	}
+	var messageID, userID stringID
+	var sender *struct {
+		UserID   stringID `json:"user_id"`
+		Nickname string   `json:"nickname"`
+	}
+	if err := unmarshalRaw(raw.MessageID, &messageID); err != nil {
+		return err
+	}
+	if err := unmarshalRaw(raw.UserID, &userID); err != nil {
+		return err
+	}
+	if err := unmarshalRaw(raw.Sender, &sender); err != nil {
+		return err
+	}
+	update.GuildMessageID = string(messageID)
+	update.UserID, _ = strconv.ParseInt(string(userID), 10, 64)
+	if sender != nil {
+		id, _ := strconv.ParseInt(string(sender.UserID), 10, 64)
+		update.Sender = &User{ID: id, NickName: sender.Nickname}
+	}
+	return nil
+}
+
+// unmarshalRaw decodes data into v, unless the field of data is missing.
+func unmarshalRaw(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
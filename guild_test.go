@@ -0,0 +1,55 @@
+package qqbotapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpdate_GuildMessage(t *testing.T) {
+	var update, other Update
+	err1 := json.Unmarshal([]byte(`{"post_type":"message","message_type":"guild","sub_type":"channel","guild_id":"100","channel_id":"200","self_tiny_id":"300","user_id":"144115218677563300","message_id":"BAACM7rOxj8X","message":"hi","sender":{"user_id":"144115218677563300","nickname":"nick"}}`), &update)
+	err2 := json.Unmarshal([]byte(`{"post_type":"message","message_type":"group","group_id":10000,"user_id":100000,"message_id":12,"message":"\"guild\""}`), &other)
+	update.ParseRawMessage()
+
+	chat := update.Message.Chat
+	if err1 == nil && err2 == nil && chat.IsGuild() && chat.GuildID == "100" && chat.ChannelID == "200" &&
+		update.GuildMessageID == "BAACM7rOxj8X" && update.Message.From.ID == 144115218677563300 && update.Message.From.NickName == "nick" && update.Text == "hi" &&
+		other.MessageID == 12 && other.UserID == 100000 {
+		t.Log("TestUpdate_GuildMessage passed")
+	} else {
+		t.Errorf("TestUpdate_GuildMessage failed: %v %v %+v %+v", err1, err2, update, other)
+	}
+}
+
+func TestGuildMessage_NotStored(t *testing.T) {
+	bot := &BotAPI{MessageStore: NewMemoryMessageStore(10)}
+	var update Update
+	json.Unmarshal([]byte(`{"post_type":"message","message_type":"guild","guild_id":"100","channel_id":"200","user_id":"300","message_id":"BAACM7rOxj8X","message":"[CQ:reply,id=1]hi"}`), &update)
+	bot.prepareUpdate(&update, BaseUpdateConfig{ResolveReply: true})
+
+	stored, _ := bot.MessageStore.Get(0)
+	sent := bot.ChatSender(update.Message.Chat).Text("hi").Send()
+	_, invalid := sent.Err.(*ValidationError)
+	if stored == nil && update.Message.ReplyTo == nil && invalid {
+		t.Log("TestGuildMessage_NotStored passed")
+	} else {
+		t.Errorf("TestGuildMessage_NotStored failed: %v %v", stored, sent.Err)
+	}
+}
+
+func TestGetGuildMemberList(t *testing.T) {
+	server := newTestServer(map[string]interface{}{
+		"members":    []map[string]interface{}{{"tiny_id": "144115218677563300", "nickname": "nick", "role_id": "1", "role_name": "member"}},
+		"finished":   false,
+		"next_token": "token",
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	list, err := bot.GetGuildMemberList("100", "")
+	if err == nil && len(list.Members) == 1 && list.Members[0].TinyID == "144115218677563300" && !list.Finished && list.NextToken == "token" {
+		t.Log("TestGetGuildMemberList passed")
+	} else {
+		t.Errorf("TestGetGuildMemberList failed: %v %v", err, list)
+	}
+}
@@ -1,12 +1,9 @@
 package qqbotapi
 
 import (
-	"encoding/base64"
-	"errors"
 	"fmt"
 	"github.com/catsworld/qq-bot-api/cqcode"
-	"io"
-	"io/ioutil"
+	"net/http"
 	"net/url"
 	"reflect"
 )
@@ -47,6 +44,47 @@ func NewMessage(chatID int64, chatType string, message interface{}) MessageConfi
 	return mc
 }
 
+// NewPrivateMessage creates a new message sent to a user with send_private_msg.
+//
+// Set GroupID of the config to reply in a temporary session from a group.
+func NewPrivateMessage(userID int64, message interface{}) PrivateMessageConfig {
+	return PrivateMessageConfig{MessageConfig: NewMessage(userID, ChatTypePrivate, message)}
+}
+
+// NewGroupMessage creates a new message sent to a group with send_group_msg.
+func NewGroupMessage(groupID int64, message interface{}) GroupMessageConfig {
+	return GroupMessageConfig{MessageConfig: NewMessage(groupID, ChatTypeGroup, message)}
+}
+
+// NewForwardMessage creates a merged forward message of nodes to a group or a user.
+func NewForwardMessage(chatID int64, chatType string, nodes ...ForwardNode) ForwardMessageConfig {
+	return ForwardMessageConfig{
+		BaseChat: BaseChat{
+			ChatID:   chatID,
+			ChatType: chatType,
+		},
+		Nodes: nodes,
+	}
+}
+
+// NewForwardNode creates a custom node of a forward message, which looks like
+// message sent by the user of userID and nickname.
+//
+// message could be any type accepted by NewMessage.
+func NewForwardNode(nickname string, userID int64, message interface{}) ForwardNode {
+	content, _ := cqcode.ParseMessageFromString(NewMessage(0, "", message).Text)
+	return ForwardNode{
+		UserID:   userID,
+		Nickname: nickname,
+		Message:  content,
+	}
+}
+
+// NewForwardNodeRef creates a node of a forward message referring to an existing message.
+func NewForwardNodeRef(messageID int64) ForwardNode {
+	return ForwardNode{MessageID: messageID}
+}
+
 // NewUpdate gets updates since the last Offset.
 //
 // offset is the last Update ID to include.
@@ -72,141 +110,99 @@ func NewWebhook(pattern string) WebhookConfig {
 	}
 }
 
-const (
-	cacheEnabled  = 1
-	cacheDisabled = 0
-)
-
 // NetResource is a resource located in the Internet.
-type NetResource struct {
-	Cache int `cq:"cache"`
-}
-
-// EnableCache enables CQ HTTP's cache feature.
-func (r *NetResource) EnableCache() {
-	r.Cache = cacheEnabled
-}
-
-// DisableCache forces CQ HTTP download from the URL instead of using cache.
-func (r *NetResource) DisableCache() {
-	r.Cache = cacheDisabled
-}
+//
+// Deprecated: Use cqcode.NetResource instead.
+type NetResource = cqcode.NetResource
 
 // NetImage is an image located in the Internet.
-type NetImage struct {
-	*cqcode.Image
-	*NetResource
-}
+//
+// Deprecated: Use cqcode.NetImage instead.
+type NetImage = cqcode.NetImage
 
 // NetRecord is a record located in the Internet.
-type NetRecord struct {
-	*cqcode.Record
-	*NetResource
-}
+//
+// Deprecated: Use cqcode.NetRecord instead.
+type NetRecord = cqcode.NetRecord
+
+// ImageOptions describes how an image is preprocessed before it is encoded in base64.
+//
+// Deprecated: Use cqcode.ImageOptions instead.
+type ImageOptions = cqcode.ImageOptions
 
 // NewImageBase64 formats an image in base64.
-func NewImageBase64(file interface{}) (*cqcode.Image, error) {
-	fileid, err := NewFileBase64(file)
-	if err != nil {
-		return &cqcode.Image{}, err
-	}
-	return &cqcode.Image{
-		FileID: fileid,
-	}, nil
+//
+// Deprecated: Use cqcode.NewImageBase64 instead.
+func NewImageBase64(file interface{}, options ...ImageOptions) (*cqcode.Image, error) {
+	return cqcode.NewImageBase64(file, options...)
 }
 
 // NewRecordBase64 formats a record in base64.
+//
+// Deprecated: Use cqcode.NewRecordBase64 instead.
 func NewRecordBase64(file interface{}) (*cqcode.Record, error) {
-	fileid, err := NewFileBase64(file)
-	if err != nil {
-		return &cqcode.Record{}, err
-	}
-	return &cqcode.Record{
-		FileID: fileid,
-	}, nil
+	return cqcode.NewRecordBase64(file)
 }
 
 // NewFileBase64 formats a file into base64 format.
+//
+// Deprecated: Use cqcode.NewFileBase64 instead.
 func NewFileBase64(file interface{}) (string, error) {
-	switch f := file.(type) {
-	case string:
-		data, err := ioutil.ReadFile(f)
-		if err != nil {
-			return "", err
-		}
-		encodeString := base64.StdEncoding.EncodeToString(data)
-		return "base64://" + encodeString, nil
-
-	case []byte:
-
-		encodeString := base64.StdEncoding.EncodeToString(f)
-		return "base64://" + encodeString, nil
-
-	case io.Reader:
-
-		data, err := ioutil.ReadAll(f)
-		if err != nil {
-			return "", err
-		}
-
-		encodeString := base64.StdEncoding.EncodeToString(data)
-		return "base64://" + encodeString, nil
-
-	default:
-		return "", errors.New("bad file type")
-	}
+	return cqcode.NewFileBase64(file)
 }
 
-// NewImageLocal formats an image with the file path,
-// this requires CQ HTTP runs in the same host with your bot.
+// NewImageLocal formats an image with the file path.
 //
-// This method is deprecated and will get removed, see #11.
-// Please use NewImageWeb instead.
+// Deprecated: Use cqcode.NewImageWeb instead, see #11.
 func NewImageLocal(file string) *cqcode.Image {
-	return &cqcode.Image{
-		FileID: NewFileLocal(file),
-	}
+	return cqcode.NewImageLocal(file)
 }
 
-// NewRecordLocal formats a record with the file path,
-// this requires CQ HTTP runs in the same host with your bot.
+// NewRecordLocal formats a record with the file path.
 //
-// This method is deprecated and will get removed, see #11.
-// Please use NewRecordWeb instead.
+// Deprecated: Use cqcode.NewRecordWeb instead, see #11.
 func NewRecordLocal(file string) *cqcode.Record {
-	return &cqcode.Record{
-		FileID: NewFileLocal(file),
-	}
+	return cqcode.NewRecordLocal(file)
 }
 
 // NewFileLocal formats a file with the file path, returning the string.
 //
-// This method is deprecated and will get removed, see #11.
-// Please use NewFileWeb instead.
+// Deprecated: Use cqcode.NewImageWeb or cqcode.NewRecordWeb instead, see #11.
 func NewFileLocal(file string) string {
-	return "file://" + file
+	return cqcode.NewFileLocal(file)
 }
 
 // NewImageWeb formats an image with the URL.
+//
+// Deprecated: Use cqcode.NewImageWeb instead.
 func NewImageWeb(url *url.URL) *NetImage {
-	return &NetImage{
-		Image: &cqcode.Image{
-			FileID: url.String(),
-		},
-		NetResource: &NetResource{
-			Cache: cacheEnabled,
-		},
-	}
+	return cqcode.NewImageWeb(url)
 }
 
 // NewRecordWeb formats a record with the URL.
+//
+// Deprecated: Use cqcode.NewRecordWeb instead.
 func NewRecordWeb(url *url.URL) *NetRecord {
-	return &NetRecord{
-		Record: &cqcode.Record{
-			FileID: url.String(),
-		},
-		NetResource: &NetResource{
-			Cache: cacheEnabled,
-		},
-	}
+	return cqcode.NewRecordWeb(url)
+}
+
+// NewImageURL formats an image with the URL, which could be a string, *url.URL or url.URL.
+//
+// Deprecated: Use cqcode.NewImageURL instead.
+func NewImageURL(u interface{}) (*NetImage, error) {
+	return cqcode.NewImageURL(u)
+}
+
+// NewRecordURL formats a record with the URL, which could be a string, *url.URL or url.URL.
+//
+// Deprecated: Use cqcode.NewRecordURL instead.
+func NewRecordURL(u interface{}) (*NetRecord, error) {
+	return cqcode.NewRecordURL(u)
+}
+
+// NewImageFromHTTPResponse downloads an image from the response and formats it in base64.
+//
+// Deprecated: Use cqcode.NewImageFromHTTPResponse instead.
+func NewImageFromHTTPResponse(resp *http.Response, options ...ImageOptions) (*cqcode.Image, error) {
+	return cqcode.NewImageFromHTTPResponse(resp, options...)
 }
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"github.com/catsworld/qq-bot-api/cqcode"
 	"net/url"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewImageWeb(t *testing.T) {
@@ -32,3 +34,105 @@ func TestNewMessage(t *testing.T) {
 		t.Errorf("TestNewMessage failed: %v", string(b))
 	}
 }
+
+func TestMessageTransformer(t *testing.T) {
+	bot := &BotAPI{}
+	bot.UseMessageTransformer(
+		StripSegments(func(chat BaseChat) []string {
+			if chat.ChatType == "group" {
+				return []string{"image"}
+			}
+			return nil
+		}),
+		AutoEscape("face"),
+		AppendAttribution("-- bot"),
+	)
+	msg := bot.transformMessage(NewMessage(123, "group", "[CQ:at,qq=all][CQ:face,id=14][CQ:image,file=1.jpg]hi"))
+	if msg.Text == "&#91;CQ:at,qq=all&#93;[CQ:face,id=14]hi\n-- bot" {
+		t.Log("TestMessageTransformer passed")
+	} else {
+		t.Errorf("TestMessageTransformer failed: %v", msg.Text)
+	}
+}
+
+func TestMessageTransformer_Concurrent(t *testing.T) {
+	bot := &BotAPI{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			bot.UseMessageTransformer(AutoEscape())
+		}()
+		go func() {
+			defer wg.Done()
+			bot.transformMessage(NewMessage(123, "group", "hi"))
+		}()
+	}
+	wg.Wait()
+	msg := bot.transformMessage(NewMessage(123, "group", "[CQ:face,id=14]"))
+	if msg.Text != "[CQ:face,id=14]" {
+		t.Log("TestMessageTransformer_Concurrent passed")
+	} else {
+		t.Errorf("TestMessageTransformer_Concurrent failed: %v", msg.Text)
+	}
+}
+
+func TestNewImageURL(t *testing.T) {
+	img, err := NewImageURL("data:image/png;base64,aGVsbG8=")
+	_, err2 := NewImageURL("ftp://img.rikako.moe/i/D1D.jpg")
+	if err == nil && img.FileID == "base64://aGVsbG8=" && err2 != nil {
+		t.Log("TestNewImageURL passed")
+	} else {
+		t.Errorf("TestNewImageURL failed: %v %v", err, err2)
+	}
+}
+
+func TestNetResource(t *testing.T) {
+	u, _ := url.Parse("https://img.rikako.moe/i/D1D.jpg")
+	img := NewImageWeb(u)
+	img.ViaProxy(false)
+	img.WithTimeout(1500 * time.Millisecond)
+	msg := NewMessage(123, "whatever", img)
+	if msg.Text == "[CQ:image,file=https://img.rikako.moe/i/D1D.jpg,url=,cache=1,proxy=0,timeout=2]" {
+		t.Log("TestNetResource passed")
+	} else {
+		t.Errorf("TestNetResource failed: %v", msg.Text)
+	}
+}
+
+func TestUpdate_GroupInvite(t *testing.T) {
+	var update Update
+	json.Unmarshal([]byte(`{"post_type":"request","request_type":"group","sub_type":"invite","group_id":10000,"user_id":100000,"comment":"hi","flag":"flag"}`), &update)
+	invite := update.GroupInvite()
+	if invite != nil && invite.InviterID == 100000 && invite.GroupID == 10000 && invite.Flag == "flag" && update.GroupJoinRequest() == nil && update.FriendRequest() == nil {
+		t.Log("TestUpdate_GroupInvite passed")
+	} else {
+		t.Errorf("TestUpdate_GroupInvite failed: %v", invite)
+	}
+}
+
+func TestUpdate_GroupCardChange(t *testing.T) {
+	var card, status Update
+	json.Unmarshal([]byte(`{"post_type":"notice","notice_type":"group_card","group_id":10000,"user_id":100000,"card_old":"old","card_new":"new"}`), &card)
+	json.Unmarshal([]byte(`{"post_type":"notice","notice_type":"client_status","client":{"app_id":537000000,"device_name":"iPad","device_kind":"iPad"},"online":true}`), &status)
+	change := card.GroupCardChange()
+	client := status.ClientStatus()
+	if change != nil && change.CardOld == "old" && change.CardNew == "new" && change.UserID == 100000 &&
+		client != nil && client.Online && client.Client.Name == "iPad" && card.ClientStatus() == nil && status.GroupCardChange() == nil {
+		t.Log("TestUpdate_GroupCardChange passed")
+	} else {
+		t.Errorf("TestUpdate_GroupCardChange failed: %v %v", change, client)
+	}
+}
+
+func TestUpdate_EssenceChange(t *testing.T) {
+	var update Update
+	json.Unmarshal([]byte(`{"post_type":"notice","notice_type":"essence","sub_type":"add","group_id":10000,"sender_id":100000,"operator_id":100001,"message_id":12}`), &update)
+	change := update.EssenceChange()
+	if change != nil && change.SubType == "add" && change.GroupID == 10000 && change.SenderID == 100000 && change.OperatorID == 100001 && change.MessageID == 12 {
+		t.Log("TestUpdate_EssenceChange passed")
+	} else {
+		t.Errorf("TestUpdate_EssenceChange failed: %v", change)
+	}
+}
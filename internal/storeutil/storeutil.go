@@ -0,0 +1,59 @@
+// Package storeutil encodes the values kept by the persistent stores.
+package storeutil
+
+import (
+	"encoding/json"
+	"github.com/catsworld/qq-bot-api"
+	"github.com/catsworld/qq-bot-api/cqcode"
+)
+
+// message is the stored form of a qqbotapi.Message, whose segments are kept
+// as the CQ string in Text, so that the messages stored before qqbotapi.Message
+// was encoded in JSON with its segments are still decoded.
+type message struct {
+	MessageID  int64          `json:"message_id"`
+	From       *qqbotapi.User `json:"from"`
+	Chat       *qqbotapi.Chat `json:"chat"`
+	Text       string         `json:"text"`
+	SubType    string         `json:"sub_type"`
+	Font       int            `json:"font"`
+	Time       int64          `json:"time"`
+	MessageSeq int64          `json:"message_seq"`
+}
+
+// EncodeMessage encodes a message in JSON.
+func EncodeMessage(m *qqbotapi.Message) ([]byte, error) {
+	return json.Marshal(message{
+		MessageID:  m.MessageID,
+		From:       m.From,
+		Chat:       m.Chat,
+		Text:       m.Text,
+		SubType:    m.SubType,
+		Font:       m.Font,
+		Time:       m.Time,
+		MessageSeq: m.MessageSeq,
+	})
+}
+
+// DecodeMessage decodes a message encoded by EncodeMessage.
+func DecodeMessage(data []byte) (*qqbotapi.Message, error) {
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	segments, err := cqcode.ParseMessageFromString(m.Text)
+	if err != nil {
+		return nil, err
+	}
+	return &qqbotapi.Message{
+		Message:    &segments,
+		MessageID:  m.MessageID,
+		From:       m.From,
+		Chat:       m.Chat,
+		Text:       m.Text,
+		SubType:    m.SubType,
+		Font:       m.Font,
+		Time:       m.Time,
+		MessageSeq: m.MessageSeq,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package qqbotapi
+
+import (
+	"time"
+)
+
+// MaxDailyLikes is the most likes a user can receive from the bot in a day.
+const MaxDailyLikes = 10
+
+// LikeLocation is the time zone where the daily limit of likes resets.
+var LikeLocation = time.FixedZone("CST", 8*60*60)
+
+// timeNow returns the current time, and is replaced in tests.
+var timeNow = time.Now
+
+// LikeDaily sends totalTimes likes to a user, spread across days because of MaxDailyLikes.
+//
+// Likes of today are sent before it returns, and the rest are sent by a goroutine
+// at the start of each day until StopReceivingUpdates is called. The progress is kept
+// in bot.LikeStore, or in memory if it is nil, and calling LikeDaily again adds to the remaining likes.
+// Call ResumeLikes after a restart to continue unfinished progresses.
+func (bot *BotAPI) LikeDaily(userID int64, totalTimes int) error {
+	store := bot.likeStore()
+	bot.likeMux.Lock()
+	progress, err := store.GetLikeProgress(userID)
+	if err != nil {
+		bot.likeMux.Unlock()
+		return err
+	}
+	if progress == nil {
+		progress = &LikeProgress{UserID: userID}
+	}
+	progress.Remaining += totalTimes
+	err = store.PutLikeProgress(progress)
+	bot.likeMux.Unlock()
+	if err != nil {
+		return err
+	}
+	return bot.startLikes(userID)
+}
+
+// ResumeLikes continues the unfinished progresses in bot.LikeStore.
+func (bot *BotAPI) ResumeLikes() error {
+	progresses, err := bot.likeStore().LikeProgresses()
+	if err != nil {
+		return err
+	}
+	for _, progress := range progresses {
+		if err := bot.startLikes(progress.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// likeStore returns bot.LikeStore, which is created in memory if it is nil.
+func (bot *BotAPI) likeStore() LikeStore {
+	bot.likeMux.Lock()
+	defer bot.likeMux.Unlock()
+	if bot.LikeStore == nil {
+		bot.LikeStore = NewMemoryLikeStore()
+	}
+	return bot.LikeStore
+}
+
+// startLikes sends likes of today to a user, and starts a goroutine sending
+// the rest unless one is already running for the user.
+func (bot *BotAPI) startLikes(userID int64) error {
+	remaining, err := bot.likeToday(userID)
+	if err != nil || remaining == 0 {
+		return err
+	}
+	bot.likeMux.Lock()
+	if bot.likeRunning == nil {
+		bot.likeRunning = make(map[int64]bool)
+	}
+	if bot.likeRunning[userID] {
+		bot.likeMux.Unlock()
+		return nil
+	}
+	bot.likeRunning[userID] = true
+	bot.likeMux.Unlock()
+
+	ctx := bot.stopContext()
+	go func() {
+		defer bot.recoverPanic("LikeDaily")
+		defer func() {
+			bot.likeMux.Lock()
+			delete(bot.likeRunning, userID)
+			bot.likeMux.Unlock()
+		}()
+		for {
+			t := time.NewTimer(untilNextDay(timeNow()))
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return
+			}
+			remaining, err := bot.likeToday(userID)
+			if err != nil {
+				bot.debugLog("LikeDaily", userID, err)
+			}
+			if err == nil && remaining == 0 {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// likeToday sends likes of today to a user if not sent yet, returning the remaining likes.
+//
+// Today is claimed in the progress before sending, so that the likes are not sent twice
+// while bot.likeMux is released, and given back if sending fails.
+func (bot *BotAPI) likeToday(userID int64) (int, error) {
+	today := timeNow().In(LikeLocation).Format("2006-01-02")
+	bot.likeMux.Lock()
+	progress, err := bot.LikeStore.GetLikeProgress(userID)
+	if err != nil || progress == nil {
+		bot.likeMux.Unlock()
+		return 0, err
+	}
+	if progress.LastDay == today {
+		bot.likeMux.Unlock()
+		return progress.Remaining, nil
+	}
+	lastDay := progress.LastDay
+	times := progress.Remaining
+	if times > MaxDailyLikes {
+		times = MaxDailyLikes
+	}
+	progress.LastDay = today
+	err = bot.LikeStore.PutLikeProgress(progress)
+	bot.likeMux.Unlock()
+	if err != nil {
+		return progress.Remaining, err
+	}
+
+	_, likeErr := bot.Like(userID, times)
+
+	bot.likeMux.Lock()
+	defer bot.likeMux.Unlock()
+	// LikeDaily might have added to the progress meanwhile
+	progress, err = bot.LikeStore.GetLikeProgress(userID)
+	if err != nil {
+		return 0, err
+	}
+	if progress == nil {
+		return 0, likeErr
+	}
+	if likeErr != nil {
+		progress.LastDay = lastDay
+		if err := bot.LikeStore.PutLikeProgress(progress); err != nil {
+			bot.debugLog("LikeDaily", userID, err)
+		}
+		return progress.Remaining, likeErr
+	}
+	progress.Remaining -= times
+	return progress.Remaining, bot.LikeStore.PutLikeProgress(progress)
+}
+
+// untilNextDay returns the duration from t to the start of the next day in LikeLocation.
+func untilNextDay(t time.Time) time.Duration {
+	t = t.In(LikeLocation)
+	next := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, LikeLocation)
+	return next.Sub(t) + time.Minute
+}
@@ -0,0 +1,57 @@
+package qqbotapi
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger logs the traffic and the errors of a bot.
+//
+// Args are alternating keys and values like those of log/slog, so that
+// a *slog.Logger is a Logger, see also NewSlogLogger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// stdLogger is the Logger used if BotAPI.Logger is not set, which prints with the log package.
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, args ...interface{}) { printLog("DEBUG", msg, args) }
+func (stdLogger) Info(msg string, args ...interface{})  { printLog("INFO", msg, args) }
+func (stdLogger) Warn(msg string, args ...interface{})  { printLog("WARN", msg, args) }
+func (stdLogger) Error(msg string, args ...interface{}) { printLog("ERROR", msg, args) }
+
+// printLog prints a line like "WARN msg key=value".
+func printLog(level string, msg string, args []interface{}) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%+v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %+v", args[i])
+		}
+	}
+	log.Println(b.String())
+}
+
+// logger returns bot.Logger, or the standard logger if it is not set.
+func (bot *BotAPI) logger() Logger {
+	if bot.Logger != nil {
+		return bot.Logger
+	}
+	return stdLogger{}
+}
+
+// debugLog logs the details at debug level if bot.Debug is set.
+func (bot *BotAPI) debugLog(context string, details ...interface{}) {
+	if bot.Debug {
+		bot.logger().Debug(context, "details", details)
+	}
+}
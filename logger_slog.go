@@ -0,0 +1,14 @@
+//go:build go1.21
+
+package qqbotapi
+
+import (
+	"log/slog"
+)
+
+// NewSlogLogger returns a Logger writing to handler, e.g.
+//
+//	bot.Logger = qqbotapi.NewSlogLogger(slog.NewJSONHandler(os.Stderr, nil))
+func NewSlogLogger(handler slog.Handler) Logger {
+	return slog.New(handler)
+}
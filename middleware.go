@@ -0,0 +1,49 @@
+package qqbotapi
+
+import (
+	"context"
+)
+
+// Invoker makes an API request, which is either the next middleware or the request itself.
+type Invoker func(ctx context.Context, endpoint string, params Params) (APIResponse, error)
+
+// RequestMiddleware intercepts API requests made with MakeRequest,
+// e.g. to log, to count, to rewrite params, or to skip requests in a dry run
+// by not calling next.
+type RequestMiddleware func(ctx context.Context, endpoint string, params Params, next Invoker) (APIResponse, error)
+
+// UseRequestMiddleware adds middlewares, the first of which is the outermost.
+// It is safe to call while requests are made, which skip the middlewares added meanwhile.
+//
+// Streamed responses, e.g. of StreamGroupMemberList, are passed to the middlewares without data.
+func (bot *BotAPI) UseRequestMiddleware(middlewares ...RequestMiddleware) {
+	bot.useMux.Lock()
+	defer bot.useMux.Unlock()
+	bot.middlewares = append(bot.middlewares[:len(bot.middlewares):len(bot.middlewares)], middlewares...)
+}
+
+// invoke makes a request through the middlewares of the bot.
+func (bot *BotAPI) invoke(ctx context.Context, endpoint string, params Params) (APIResponse, error) {
+	bot.useMux.RLock()
+	middlewares := bot.middlewares
+	bot.useMux.RUnlock()
+	invoker := bot.makeTransportRequest
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, next := middlewares[i], invoker
+		invoker = func(ctx context.Context, endpoint string, params Params) (APIResponse, error) {
+			return middleware(ctx, endpoint, params, next)
+		}
+	}
+	return invoker(ctx, endpoint, params)
+}
+
+// makeTransportRequest makes a request over HTTP or websocket.
+func (bot *BotAPI) makeTransportRequest(ctx context.Context, endpoint string, params Params) (APIResponse, error) {
+	if each, ok := streamOf(ctx); ok {
+		return bot.makeStreamRequest(ctx, endpoint, params, each)
+	}
+	if bot.Client != nil {
+		return bot.makeHTTPRequest(ctx, endpoint, params)
+	}
+	return bot.makeWSRequest(ctx, endpoint, params)
+}
@@ -0,0 +1,87 @@
+package qqbotapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// BotGroup routes the updates reported to the same endpoint by several accounts
+// to the BotAPI of each account, by Update.SelfID.
+//
+// The updates are verified and prepared by the bot of their accounts,
+// and the bot replying an update is found with Bot(update.SelfID).
+type BotGroup struct {
+	Buffer int // of the channels returned by the listeners
+
+	bots map[int64]*BotAPI
+	mux  sync.RWMutex
+}
+
+// NewBotGroup creates a BotGroup of bots, whose accounts are their Self.ID.
+func NewBotGroup(bots ...*BotAPI) *BotGroup {
+	g := &BotGroup{
+		bots: make(map[int64]*BotAPI),
+	}
+	for _, bot := range bots {
+		g.Add(bot)
+	}
+	return g
+}
+
+// Add adds a bot, whose account is its Self.ID.
+func (g *BotGroup) Add(bot *BotAPI) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.bots[bot.Self.ID] = bot
+}
+
+// Bot returns the bot of an account, or nil if it is unknown.
+func (g *BotGroup) Bot(selfID int64) *BotAPI {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+	return g.bots[selfID]
+}
+
+// ListenForWebhook registers a http handler for a webhook of all accounts on mux,
+// e.g. http.DefaultServeMux, and returns a channel that gets their updates.
+//
+// Requests of unknown accounts are rejected.
+func (g *BotGroup) ListenForWebhook(mux *http.ServeMux, config WebhookConfig) UpdatesChannel {
+	ch := make(chan Update, g.Buffer)
+
+	mux.HandleFunc(config.Pattern, func(w http.ResponseWriter, r *http.Request) {
+		readBody(r, func(data []byte) {
+			var self struct {
+				SelfID int64 `json:"self_id"`
+			}
+			json.Unmarshal(data, &self)
+			if self.SelfID == 0 {
+				self.SelfID = headerSelfID(r)
+			}
+			bot := g.Bot(self.SelfID)
+			if bot == nil {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			bot.serveWebhook(config, w, r, data, func(update Update) {
+				ch <- update
+				w.WriteHeader(http.StatusNoContent)
+			})
+		})
+	})
+
+	return ch
+}
+
+// ListenForWebSocket registers a http handler for a websocket of all accounts on mux,
+// e.g. http.DefaultServeMux, and returns a channel that gets their updates.
+//
+// Connections are accepted by the bot of their X-Self-ID header, and rejected if it is unknown.
+func (g *BotGroup) ListenForWebSocket(mux *http.ServeMux, config WebhookConfig) UpdatesChannel {
+	ch := make(chan Update, g.Buffer)
+
+	mux.Handle(config.Pattern, websocketServer(config, ch, g.Bot))
+
+	return ch
+}
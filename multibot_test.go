@@ -0,0 +1,58 @@
+package qqbotapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBotGroup_ListenForWebhook(t *testing.T) {
+	bot1 := &BotAPI{Self: User{ID: 10000}, Secret: "secret1"}
+	bot2 := &BotAPI{Self: User{ID: 20000}, Secret: "secret2"}
+	g := NewBotGroup(bot1, bot2)
+	g.Buffer = 10
+	mux := http.NewServeMux()
+	updates := g.ListenForWebhook(mux, WebhookConfig{Pattern: "/multibot"})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	post := func(body string, secret string) int {
+		mac := hmac.New(sha1.New, []byte(secret))
+		mac.Write([]byte(body))
+		req, _ := http.NewRequest("POST", server.URL+"/multibot", strings.NewReader(body))
+		req.Header.Set("X-Signature", "sha1="+hex.EncodeToString(mac.Sum(nil)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	codes := []int{
+		post(`{"self_id":20000,"post_type":"message","message_type":"private","user_id":1,"message":"hi"}`, "secret2"),
+		post(`{"self_id":10000,"post_type":"message","message_type":"private","user_id":1,"message":"hi"}`, "secret2"),
+		post(`{"self_id":30000,"post_type":"message","message_type":"private","user_id":1,"message":"hi"}`, "secret2"),
+	}
+
+	update := <-updates
+	if codes[0] == http.StatusNoContent && codes[1] == http.StatusForbidden && codes[2] == http.StatusForbidden &&
+		update.SelfID == 20000 && g.Bot(update.SelfID) == bot2 {
+		t.Log("TestBotGroup_ListenForWebhook passed")
+	} else {
+		t.Errorf("TestBotGroup_ListenForWebhook failed: %v %v", codes, update.SelfID)
+	}
+}
+
+func TestWSUpdateOfBot(t *testing.T) {
+	bot := &BotAPI{Self: User{ID: 10000}}
+	if wsUpdateOfBot(bot, Update{SelfID: 10000}) && wsUpdateOfBot(bot, Update{}) &&
+		!wsUpdateOfBot(bot, Update{SelfID: 20000}) && wsUpdateOfBot(&BotAPI{}, Update{SelfID: 20000}) {
+		t.Log("TestWSUpdateOfBot passed")
+	} else {
+		t.Errorf("TestWSUpdateOfBot failed")
+	}
+}
@@ -0,0 +1,93 @@
+package qqbotapi
+
+import (
+	"time"
+)
+
+// APIErrorEvent describes a failed API call.
+type APIErrorEvent struct {
+	Endpoint string
+	RetCode  int // 0 if no response is received
+	Err      error
+	Count    int // times of the retcode within the window, only for alerts
+}
+
+// ErrorObserver is notified of failed API calls.
+type ErrorObserver func(event APIErrorEvent)
+
+// retCodeAlert notifies its observer when a retcode repeats threshold times within window.
+type retCodeAlert struct {
+	retCode   int
+	threshold int
+	window    time.Duration
+	observer  ErrorObserver
+	times     []time.Time
+}
+
+// ObserveErrors adds observers notified on every failed API call,
+// e.g. to log errors or to send them to an admin chat.
+//
+// Observers are called synchronously, and should not make API calls
+// which fail again without a guard.
+func (bot *BotAPI) ObserveErrors(observers ...ErrorObserver) {
+	bot.observerMux.Lock()
+	defer bot.observerMux.Unlock()
+	bot.observers = append(bot.observers, observers...)
+}
+
+// AlertOnRetCode adds an observer notified when API calls fail with retCode
+// threshold times within window, e.g. many "not enough permission" errors.
+//
+// The count is reset after each notification.
+func (bot *BotAPI) AlertOnRetCode(retCode int, threshold int, window time.Duration, observer ErrorObserver) {
+	bot.observerMux.Lock()
+	defer bot.observerMux.Unlock()
+	bot.alerts = append(bot.alerts, &retCodeAlert{
+		retCode:   retCode,
+		threshold: threshold,
+		window:    window,
+		observer:  observer,
+	})
+}
+
+// observeError notifies observers and alerts of a failed API call.
+func (bot *BotAPI) observeError(endpoint string, resp APIResponse, err error) {
+	event := APIErrorEvent{
+		Endpoint: endpoint,
+		RetCode:  resp.RetCode,
+		Err:      err,
+	}
+	now := time.Now()
+
+	bot.observerMux.Lock()
+	observers := bot.observers
+	var alerts []ErrorObserver
+	var counts []int
+	for _, alert := range bot.alerts {
+		if event.RetCode == 0 || alert.retCode != event.RetCode {
+			continue
+		}
+		times := alert.times[:0]
+		for _, t := range alert.times {
+			if now.Sub(t) < alert.window {
+				times = append(times, t)
+			}
+		}
+		alert.times = append(times, now)
+		if len(alert.times) >= alert.threshold {
+			alerts = append(alerts, alert.observer)
+			counts = append(counts, len(alert.times))
+			alert.times = nil
+		}
+	}
+	bot.observerMux.Unlock()
+
+	for _, observer := range observers {
+		observer(event)
+	}
+	for i, observer := range alerts {
+		e := event
+		e.Count = counts[i]
+		observer(e)
+	}
+}
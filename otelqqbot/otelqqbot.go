@@ -0,0 +1,74 @@
+// Package otelqqbot traces the API requests and the updates of qqbotapi with OpenTelemetry.
+//
+//	tracer := otel.Tracer("bot")
+//	bot.UseRequestMiddleware(otelqqbot.Middleware(tracer))
+//	ev := qqbotapi.NewEv(updates, qqbotapi.EvInterceptor(otelqqbot.Interceptor(tracer)))
+//	ev.On("message.group")(func(update qqbotapi.Update) {
+//		// the reply is traced as a child of the update
+//		bot.SendWithContext(update.Context(), qqbotapi.NewMessage(update.GroupID, "group", "hi"))
+//	})
+package otelqqbot
+
+import (
+	"context"
+	"github.com/catsworld/qq-bot-api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware returns a qqbotapi.RequestMiddleware starting a client span for every API request,
+// as a child of the span in the context of the request.
+func Middleware(tracer trace.Tracer) qqbotapi.RequestMiddleware {
+	return func(ctx context.Context, endpoint string, params qqbotapi.Params, next qqbotapi.Invoker) (qqbotapi.APIResponse, error) {
+		ctx, span := tracer.Start(ctx, "qqbot "+endpoint,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("qqbot.action", endpoint)),
+		)
+		defer span.End()
+
+		resp, err := next(ctx, endpoint, params)
+		span.SetAttributes(attribute.Int("qqbot.retcode", resp.RetCode))
+		if err != nil && !qqbotapi.IsAsync(err) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return resp, err
+	}
+}
+
+// Interceptor returns an interceptor of qqbotapi.Ev starting a consumer span for every update,
+// which is passed to the handlers in the context of the update.
+func Interceptor(tracer trace.Tracer) func(update qqbotapi.Update, emit func(update qqbotapi.Update)) {
+	return func(update qqbotapi.Update, emit func(update qqbotapi.Update)) {
+		name := "qqbot " + update.PostType
+		if detail := detailedType(update); detail != "" {
+			name += "." + detail
+		}
+		ctx, span := tracer.Start(update.Context(), name,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("qqbot.post_type", update.PostType),
+				attribute.Int64("qqbot.user_id", update.UserID),
+				attribute.Int64("qqbot.group_id", update.GroupID),
+				attribute.Int64("qqbot.message_id", update.MessageID),
+			),
+		)
+		defer span.End()
+
+		emit(update.WithContext(ctx))
+	}
+}
+
+// detailedType returns the message, notice or request type of the update.
+func detailedType(update qqbotapi.Update) string {
+	switch update.PostType {
+	case "message":
+		return update.MessageType
+	case "notice":
+		return update.NoticeType
+	case "request":
+		return update.RequestType
+	}
+	return ""
+}
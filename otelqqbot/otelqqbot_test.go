@@ -0,0 +1,34 @@
+package otelqqbot
+
+import (
+	"context"
+	"github.com/catsworld/qq-bot-api"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := trace.NewTracerProvider(trace.WithSpanProcessor(recorder)).Tracer("test")
+
+	bot := &qqbotapi.BotAPI{}
+	bot.UseRequestMiddleware(
+		Middleware(tracer),
+		func(ctx context.Context, endpoint string, params qqbotapi.Params, next qqbotapi.Invoker) (qqbotapi.APIResponse, error) {
+			return qqbotapi.APIResponse{Status: "ok"}, nil
+		},
+	)
+	update := qqbotapi.Update{PostType: "message", MessageType: "group", GroupID: 10000}
+	Interceptor(tracer)(update, func(update qqbotapi.Update) {
+		bot.MakeRequestWithParams(update.Context(), "send_msg", nil)
+	})
+
+	spans := recorder.Ended()
+	if len(spans) == 2 && spans[0].Name() == "qqbot send_msg" && spans[1].Name() == "qqbot message.group" &&
+		spans[0].Parent().SpanID() == spans[1].SpanContext().SpanID() {
+		t.Log("TestTracing passed")
+	} else {
+		t.Errorf("TestTracing failed: %v", spans)
+	}
+}
@@ -0,0 +1,58 @@
+package qqbotapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicHandler is called with the value recovered from a panic in a background
+// goroutine or a handler of the bot, and the stack trace of the panic.
+type PanicHandler func(recovered interface{}, stack []byte)
+
+// PanicError is returned in place of a recovered panic, e.g. when fetching updates panics.
+type PanicError struct {
+	Recovered interface{}
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Recovered)
+}
+
+// handlePanic logs a recovered panic and passes it to bot.PanicHandler.
+func (bot *BotAPI) handlePanic(where string, recovered interface{}) {
+	stack := debug.Stack()
+	bot.logger().Error("Recovered from panic in "+where, "panic", recovered, "stack", string(stack))
+	if bot.PanicHandler != nil {
+		bot.PanicHandler(recovered, stack)
+	}
+}
+
+// recoverPanic recovers a panic and handles it, which must be deferred directly.
+func (bot *BotAPI) recoverPanic(where string) {
+	if r := recover(); r != nil {
+		bot.handlePanic(where, r)
+	}
+}
+
+// recoverHTTPPanic recovers a panic in an http handler, handles it and responds 500.
+func (bot *BotAPI) recoverHTTPPanic(where string, w http.ResponseWriter) {
+	if r := recover(); r != nil {
+		bot.handlePanic(where, r)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// getUpdatesSafely gets updates like GetUpdatesWithContext, returning a *PanicError
+// if it panics, e.g. in PreloadUserInfo or a MessageStore.
+func (bot *BotAPI) getUpdatesSafely(ctx context.Context, config UpdateConfig) (updates []Update, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			bot.handlePanic("GetUpdates", r)
+			updates, err = nil, &PanicError{Recovered: r}
+		}
+	}()
+	return bot.GetUpdatesWithContext(ctx, config)
+}
@@ -0,0 +1,108 @@
+package qqbotapi
+
+// Role is the permission level of a user, which are ordered so that a higher role
+// has the permissions of the lower ones.
+type Role int
+
+// Roles from the lowest to the highest.
+const (
+	RoleStranger  Role = iota // not in the group, or outside groups
+	RoleMember                // member of the group
+	RoleAdmin                 // admin of the group
+	RoleOwner                 // owner of the group
+	RoleSuperuser             // in bot.SuperuserStore
+)
+
+// String returns the name of the role, e.g. "admin".
+func (r Role) String() string {
+	switch r {
+	case RoleMember:
+		return "member"
+	case RoleAdmin:
+		return "admin"
+	case RoleOwner:
+		return "owner"
+	case RoleSuperuser:
+		return "superuser"
+	}
+	return "stranger"
+}
+
+// groupRole returns the Role of a role in a group, i.e. "owner", "admin" or "member".
+func groupRole(role string) Role {
+	switch role {
+	case "owner":
+		return RoleOwner
+	case "admin":
+		return RoleAdmin
+	case "member":
+		return RoleMember
+	}
+	return RoleStranger
+}
+
+// RoleOf returns the role of the user of an update, e.g. the sender of a message.
+//
+// Superusers are looked up in bot.SuperuserStore if set. In groups, the role is read from
+// Update.Sender, or fetched with GetGroupMemberInfo if the update has no sender, e.g. a notice.
+// Outside groups, other users are strangers.
+func (bot *BotAPI) RoleOf(update Update) (Role, error) {
+	if update.UserID == 0 {
+		return RoleStranger, nil
+	}
+	if bot.SuperuserStore != nil {
+		ok, err := bot.SuperuserStore.IsSuperuser(update.UserID)
+		if err != nil {
+			return RoleStranger, err
+		}
+		if ok {
+			return RoleSuperuser, nil
+		}
+	}
+	if update.GroupID == 0 {
+		return RoleStranger, nil
+	}
+	if update.Sender != nil && update.Sender.Role != "" {
+		return groupRole(update.Sender.Role), nil
+	}
+	member, err := bot.GetGroupMemberInfo(update.GroupID, update.UserID, false)
+	if err != nil {
+		return RoleStranger, err
+	}
+	return groupRole(member.Role), nil
+}
+
+// Role returns the role of the user of the update resolved with Bot.RoleOf,
+// which is cached for the update. Without Bot, it is read from Update.Sender only.
+func (c *EvContext) Role() (Role, error) {
+	if role, ok := c.Get("role"); ok {
+		return role.(Role), nil
+	}
+	var role Role
+	if c.Bot != nil {
+		var err error
+		role, err = c.Bot.RoleOf(c.Update)
+		if err != nil {
+			return role, err
+		}
+	} else if c.Update.GroupID != 0 && c.Update.Sender != nil {
+		role = groupRole(c.Update.Sender.Role)
+	}
+	c.Set("role", role)
+	return role, nil
+}
+
+// RequireRole returns a middleware passing only the updates from users of role or higher,
+// see EvContext.Role. Updates whose role fails to resolve are skipped.
+func RequireRole(role Role) EvMiddleware {
+	return func(c *EvContext, next func(c *EvContext)) {
+		r, err := c.Role()
+		if err != nil {
+			c.Bot.debugLog("RequireRole", "failed to resolve role", err)
+			return
+		}
+		if r >= role {
+			next(c)
+		}
+	}
+}
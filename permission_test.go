@@ -0,0 +1,52 @@
+package qqbotapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoleOf(t *testing.T) {
+	server := newTestServer(map[string]interface{}{"group_id": 1, "user_id": 30000, "role": "owner"})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL, SuperuserStore: NewMemorySuperuserStore(10000)}
+	var roles []Role
+	for _, update := range []Update{
+		{GroupID: 1, UserID: 10000, Sender: &User{Role: "member"}},
+		{GroupID: 1, UserID: 20000, Sender: &User{Role: "admin"}},
+		{GroupID: 1, UserID: 30000},
+		{UserID: 40000},
+	} {
+		role, err := bot.RoleOf(update)
+		if err != nil {
+			t.Fatalf("TestRoleOf failed: %v", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if reflect.DeepEqual(roles, []Role{RoleSuperuser, RoleAdmin, RoleOwner, RoleStranger}) {
+		t.Log("TestRoleOf passed")
+	} else {
+		t.Errorf("TestRoleOf failed: %v", roles)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	ev := &Ev{subscribers: make(map[string][]func(update Update))}
+	ev.Use(RequireRole(RoleAdmin))
+
+	var handled []int64
+	ev.On("message")(func(update Update) {
+		handled = append(handled, update.UserID)
+	})
+
+	ev.handle(Update{PostType: "message", GroupID: 1, UserID: 10000, Sender: &User{Role: "owner"}})
+	ev.handle(Update{PostType: "message", GroupID: 1, UserID: 20000, Sender: &User{Role: "member"}})
+	ev.handle(Update{PostType: "message", UserID: 30000})
+
+	if reflect.DeepEqual(handled, []int64{10000}) {
+		t.Log("TestRequireRole passed")
+	} else {
+		t.Errorf("TestRequireRole failed: %v", handled)
+	}
+}
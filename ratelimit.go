@@ -0,0 +1,140 @@
+package qqbotapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate of messages sent to chats, so that the account
+// is not muted for flooding. Send waits for it before sending a message.
+type RateLimiter interface {
+	// Wait blocks until a message can be sent to the chat, or returns an error if ctx is done first.
+	Wait(ctx context.Context, chat BaseChat) error
+}
+
+// Rate is a rate of messages, i.e. Messages per Per duration, which is also the burst.
+type Rate struct {
+	Messages int
+	Per      time.Duration
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// fill adds the tokens accumulated since the last fill.
+func (b *bucket) fill(rate Rate, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = float64(rate.Messages)
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * float64(rate.Messages) / rate.Per.Seconds()
+		if b.tokens > float64(rate.Messages) {
+			b.tokens = float64(rate.Messages)
+		}
+	}
+	b.last = now
+}
+
+// wait returns the duration until a token is available.
+func (b *bucket) wait(rate Rate) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(rate.Per) / float64(rate.Messages))
+}
+
+// TokenBucketLimiter is a RateLimiter with a token bucket for every chat,
+// and a global one shared by all chats.
+type TokenBucketLimiter struct {
+	perChat Rate
+	global  Rate
+
+	mux     sync.Mutex
+	chats   map[BaseChat]*bucket
+	overall bucket
+}
+
+// maxIdleBuckets is the number of chat buckets kept before full ones are dropped.
+const maxIdleBuckets = 4096
+
+// NewTokenBucketLimiter creates a TokenBucketLimiter, a zero Rate means no limit.
+//
+//	bot.RateLimiter = qqbotapi.NewTokenBucketLimiter(
+//		qqbotapi.Rate{Messages: 5, Per: 10 * time.Second},
+//		qqbotapi.Rate{Messages: 20, Per: 10 * time.Second},
+//	)
+func NewTokenBucketLimiter(perChat Rate, global Rate) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		perChat: perChat,
+		global:  global,
+		chats:   make(map[BaseChat]*bucket),
+	}
+}
+
+// Wait blocks until a message can be sent to the chat, or returns an error if ctx is done first.
+func (l *TokenBucketLimiter) Wait(ctx context.Context, chat BaseChat) error {
+	for {
+		d := l.reserve(chat)
+		if d == 0 {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token from both buckets if available, or returns the duration to wait.
+func (l *TokenBucketLimiter) reserve(chat BaseChat) time.Duration {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	now := time.Now()
+	var d time.Duration
+
+	var b *bucket
+	if l.perChat.Messages > 0 {
+		b = l.chats[chat]
+		if b == nil {
+			if len(l.chats) >= maxIdleBuckets {
+				l.dropFull(now)
+			}
+			b = &bucket{}
+			l.chats[chat] = b
+		}
+		b.fill(l.perChat, now)
+		d = b.wait(l.perChat)
+	}
+	if l.global.Messages > 0 {
+		l.overall.fill(l.global, now)
+		if w := l.overall.wait(l.global); w > d {
+			d = w
+		}
+	}
+	if d > 0 {
+		return d
+	}
+	if b != nil {
+		b.tokens--
+	}
+	if l.global.Messages > 0 {
+		l.overall.tokens--
+	}
+	return 0
+}
+
+// dropFull drops the buckets of chats which are full, i.e. idle.
+func (l *TokenBucketLimiter) dropFull(now time.Time) {
+	for chat, b := range l.chats {
+		b.fill(l.perChat, now)
+		if b.tokens >= float64(l.perChat.Messages) {
+			delete(l.chats, chat)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package qqbotapi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketLimiter(t *testing.T) {
+	l := NewTokenBucketLimiter(Rate{Messages: 2, Per: time.Second}, Rate{Messages: 3, Per: time.Second})
+	chat1 := BaseChat{ChatID: 10000, ChatType: "group"}
+	chat2 := BaseChat{ChatID: 10001, ChatType: "group"}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	errs := []error{
+		l.Wait(ctx, chat1),
+		l.Wait(ctx, chat1),
+		l.Wait(ctx, chat1), // per chat limit
+		l.Wait(ctx, chat2),
+		l.Wait(ctx, chat2), // global limit
+	}
+	if errs[0] == nil && errs[1] == nil && errs[2] != nil && errs[3] == nil && errs[4] != nil {
+		t.Log("TestTokenBucketLimiter passed")
+	} else {
+		t.Errorf("TestTokenBucketLimiter failed: %v", errs)
+	}
+}
@@ -0,0 +1,128 @@
+// Package redisstore provides the stores of qqbotapi backed by Redis,
+// so that the state survives restarts of the bot.
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/catsworld/qq-bot-api"
+	"github.com/catsworld/qq-bot-api/internal/storeutil"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// Store is a qqbotapi.MessageStore, qqbotapi.LikeStore and qqbotapi.CooldownStore backed by Redis.
+//
+//	bot.MessageStore = redisstore.New(client, "bot:", 24*time.Hour)
+type Store struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// New creates a Store keeping keys with prefix in client,
+// and messages expire after ttl, or never if it is 0.
+func New(client redis.UniversalClient, prefix string, ttl time.Duration) *Store {
+	return &Store{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (s *Store) messageKey(messageID int64) string {
+	return s.prefix + "message:" + strconv.FormatInt(messageID, 10)
+}
+
+func (s *Store) likeKey() string {
+	return s.prefix + "likes"
+}
+
+func (s *Store) cooldownKey(key string) string {
+	return s.prefix + "cooldown:" + key
+}
+
+// Put saves a message.
+func (s *Store) Put(message *qqbotapi.Message) error {
+	data, err := storeutil.EncodeMessage(message)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(context.Background(), s.messageKey(message.MessageID), data, s.ttl).Err()
+}
+
+// Get returns the message with the MessageID, or nil if it is not found.
+func (s *Store) Get(messageID int64) (*qqbotapi.Message, error) {
+	data, err := s.client.Get(context.Background(), s.messageKey(messageID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return storeutil.DecodeMessage(data)
+}
+
+// PutLikeProgress saves the progress of a user, and deletes it if nothing remains.
+func (s *Store) PutLikeProgress(progress *qqbotapi.LikeProgress) error {
+	field := strconv.FormatInt(progress.UserID, 10)
+	if progress.Remaining <= 0 {
+		return s.client.HDel(context.Background(), s.likeKey(), field).Err()
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(context.Background(), s.likeKey(), field, data).Err()
+}
+
+// GetLikeProgress returns the progress of a user, or nil if it is not found.
+func (s *Store) GetLikeProgress(userID int64) (*qqbotapi.LikeProgress, error) {
+	data, err := s.client.HGet(context.Background(), s.likeKey(), strconv.FormatInt(userID, 10)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var progress qqbotapi.LikeProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// LikeProgresses returns all the unfinished progresses.
+func (s *Store) LikeProgresses() ([]*qqbotapi.LikeProgress, error) {
+	values, err := s.client.HGetAll(context.Background(), s.likeKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	progresses := make([]*qqbotapi.LikeProgress, 0, len(values))
+	for _, data := range values {
+		var progress qqbotapi.LikeProgress
+		if err := json.Unmarshal([]byte(data), &progress); err != nil {
+			return nil, err
+		}
+		progresses = append(progresses, &progress)
+	}
+	return progresses, nil
+}
+
+// Acquire starts the cooldown of key for d and returns true if it is not cooling down,
+// or else returns false with the remaining duration of the cooldown.
+func (s *Store) Acquire(key string, d time.Duration) (bool, time.Duration, error) {
+	ok, err := s.client.SetNX(context.Background(), s.cooldownKey(key), 1, d).Result()
+	if err != nil || ok {
+		return ok, 0, err
+	}
+	remaining, err := s.client.PTTL(context.Background(), s.cooldownKey(key)).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	return false, remaining, nil
+}
@@ -0,0 +1,51 @@
+package redisstore
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/catsworld/qq-bot-api"
+	"github.com/catsworld/qq-bot-api/cqcode"
+	"github.com/redis/go-redis/v9"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := New(redis.NewClient(&redis.Options{Addr: server.Addr()}), "bot:", time.Hour)
+
+	segments, _ := cqcode.ParseMessageFromString("hi[CQ:face,id=14]")
+	s.Put(&qqbotapi.Message{Message: &segments, MessageID: 12, Text: "hi[CQ:face,id=14]", Chat: &qqbotapi.Chat{ID: 10000, Type: "group"}, Time: 1500000000, MessageSeq: 100})
+	s.PutLikeProgress(&qqbotapi.LikeProgress{UserID: 10000, Remaining: 15})
+	s.PutLikeProgress(&qqbotapi.LikeProgress{UserID: 10001, Remaining: 5})
+	s.PutLikeProgress(&qqbotapi.LikeProgress{UserID: 10001})
+
+	message, err1 := s.Get(12)
+	missing, err2 := s.Get(13)
+	progresses, err3 := s.LikeProgresses()
+	if err1 == nil && err2 == nil && err3 == nil && missing == nil && len(*message.Message) == 2 && message.Chat.IsGroup() && message.Time == 1500000000 && message.MessageSeq == 100 && len(progresses) == 1 && progresses[0].Remaining == 15 && server.TTL("bot:message:12") == time.Hour {
+		t.Log("TestStore passed")
+	} else {
+		t.Errorf("TestStore failed: %v %v %v %v %v", err1, err2, err3, message, progresses)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if message, _ := s.Get(12); message != nil {
+		t.Errorf("TestStore failed: message not expired")
+	}
+}
+
+func TestStore_Acquire(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := New(redis.NewClient(&redis.Options{Addr: server.Addr()}), "bot:", time.Hour)
+
+	ok1, _, err1 := s.Acquire("roll:user:10000", time.Minute)
+	ok2, remaining, err2 := s.Acquire("roll:user:10000", time.Minute)
+	server.FastForward(time.Minute)
+	ok3, _, err3 := s.Acquire("roll:user:10000", time.Minute)
+
+	if err1 == nil && err2 == nil && err3 == nil && ok1 && !ok2 && remaining == time.Minute && ok3 {
+		t.Log("TestStore_Acquire passed")
+	} else {
+		t.Errorf("TestStore_Acquire failed: %v %v %v %v %v %v %v", ok1, ok2, ok3, remaining, err1, err2, err3)
+	}
+}
@@ -0,0 +1,111 @@
+package qqbotapi
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ActionParams builds the params of a custom action from the arguments it is called with.
+type ActionParams func(args ...interface{}) (map[string]interface{}, error)
+
+// Action is a custom action registered with RegisterAction.
+type Action struct {
+	Name string
+
+	bot        *BotAPI
+	params     ActionParams
+	resultType reflect.Type
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterAction registers a custom action, e.g. provided by a fork of CQ HTTP,
+// and returns it. It is sent as a GenericConfig.
+//
+// result is a value of the type the data of the response is decoded into,
+// e.g. GroupMember{}, or nil if the data is not needed.
+//
+// Actions should be registered before the bot is used concurrently.
+func (bot *BotAPI) RegisterAction(name string, params ActionParams, result interface{}) *Action {
+	action := &Action{
+		Name:   name,
+		bot:    bot,
+		params: params,
+	}
+	if result != nil {
+		action.resultType = reflect.TypeOf(result)
+	}
+	if bot.actions == nil {
+		bot.actions = make(map[string]*Action)
+	}
+	bot.actions[name] = action
+	return action
+}
+
+// Action returns a custom action registered with RegisterAction, or nil if not found.
+func (bot *BotAPI) Action(name string) *Action {
+	return bot.actions[name]
+}
+
+// Call calls the action with args, returning the data of the response
+// as a value of the result type it is registered with.
+func (a *Action) Call(args ...interface{}) (interface{}, error) {
+	params := map[string]interface{}{}
+	if a.params != nil {
+		p, err := a.params(args...)
+		if err != nil {
+			return nil, err
+		}
+		params = p
+	}
+	config := GenericConfig{Action: a.Name, Params: params}
+	if a.resultType == nil {
+		return nil, a.bot.DoInto(config, nil)
+	}
+	result := reflect.New(a.resultType)
+	if err := a.bot.DoInto(config, result.Interface()); err != nil {
+		return nil, err
+	}
+	return result.Elem().Interface(), nil
+}
+
+// Bind sets fptr, which is a pointer to a function, to a typed wrapper of the action.
+//
+// The function must return an error as its last result, and may return
+// the result type the action is registered with before it, e.g.
+//
+//	var getGroupHonor func(groupID int64, honor string) (Honor, error)
+//	bot.RegisterAction("get_group_honor_info", params, Honor{}).Bind(&getGroupHonor)
+func (a *Action) Bind(fptr interface{}) error {
+	fn := reflect.ValueOf(fptr)
+	if fn.Kind() != reflect.Ptr || fn.Elem().Kind() != reflect.Func {
+		return errors.New("bind: not a pointer to a function")
+	}
+	t := fn.Elem().Type()
+	switch {
+	case t.NumOut() == 0 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType:
+		return errors.New("bind: function must return an error as its last result")
+	case t.NumOut() == 2 && (a.resultType == nil || !a.resultType.AssignableTo(t.Out(0))):
+		return errors.New("bind: result type of " + a.Name + " is not assignable to " + t.Out(0).String())
+	}
+	fn.Elem().Set(reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		args := make([]interface{}, len(in))
+		for i, arg := range in {
+			args[i] = arg.Interface()
+		}
+		result, err := a.Call(args...)
+		errValue := reflect.Zero(errorType)
+		if err != nil {
+			errValue = reflect.ValueOf(err)
+		}
+		if t.NumOut() == 1 {
+			return []reflect.Value{errValue}
+		}
+		resultValue := reflect.Zero(t.Out(0))
+		if err == nil {
+			resultValue = reflect.ValueOf(result)
+		}
+		return []reflect.Value{resultValue, errValue}
+	}))
+	return nil
+}
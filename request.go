@@ -0,0 +1,112 @@
+package qqbotapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// Params are the params of a request, which are sent as they are over websocket,
+// e.g. booleans, numbers and message arrays.
+//
+// Over HTTP, they are sent in a JSON body if any of them is a slice, map or struct,
+// or form encoded otherwise, see Values.
+type Params map[string]interface{}
+
+// requestBody is the body of a request over HTTP.
+type requestBody struct {
+	contentType string
+	data        []byte
+}
+
+// formBody returns the form encoded body of v.
+func formBody(v url.Values) requestBody {
+	return requestBody{
+		contentType: "application/x-www-form-urlencoded",
+		data:        []byte(v.Encode()),
+	}
+}
+
+// body returns the body of p sent over HTTP, which is encoded in JSON if p contains
+// slices, maps or structs, e.g. forward nodes, or form encoded otherwise.
+func (p Params) body() (requestBody, error) {
+	for _, param := range p {
+		switch reflect.ValueOf(param).Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Ptr:
+			data, err := json.Marshal(p)
+			return requestBody{contentType: "application/json", data: data}, err
+		}
+	}
+	v, err := p.Values()
+	return formBody(v), err
+}
+
+// ParamsFromValues converts url.Values to Params, keeping the first value of each key.
+func ParamsFromValues(v url.Values) Params {
+	if v == nil {
+		return nil
+	}
+	p := make(Params, len(v))
+	for k, vs := range v {
+		if len(vs) != 0 {
+			p[k] = vs[0]
+		}
+	}
+	return p
+}
+
+// Values returns the url.Values representation of p sent over HTTP.
+func (p Params) Values() (url.Values, error) {
+	v := url.Values{}
+
+	for key, param := range p {
+		switch reflect.ValueOf(param).Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Ptr:
+			b, err := json.Marshal(param)
+			if err != nil {
+				return v, err
+			}
+			v.Add(key, string(b))
+		default:
+			v.Add(key, fmt.Sprint(param))
+		}
+	}
+
+	return v, nil
+}
+
+// Request makes a request to endpoint, and decodes the data of the response into T,
+// e.g. for an endpoint which has no method yet.
+//
+//	info, err := qqbotapi.Request[map[string]interface{}](bot, "get_version_info", nil)
+func Request[T any](bot *BotAPI, endpoint string, params Params) (T, error) {
+	return RequestWithContext[T](context.Background(), bot, endpoint, params)
+}
+
+// RequestWithContext makes a request like Request, which is aborted when ctx is done.
+func RequestWithContext[T any](ctx context.Context, bot *BotAPI, endpoint string, params Params) (T, error) {
+	var result T
+	resp, err := bot.MakeRequestWithParams(ctx, endpoint, params)
+	if err != nil {
+		return result, err
+	}
+	err = decodeData(endpoint, resp.Data, &result)
+
+	bot.debugLog(endpoint, params, result)
+
+	return result, err
+}
+
+// decodeData decodes the data of a response of endpoint into v, which is left as is
+// if there is no data. A *DecodeError is returned if it fails.
+func decodeData(endpoint string, data json.RawMessage, v interface{}) error {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &DecodeError{Endpoint: endpoint, Err: err}
+	}
+	return nil
+}
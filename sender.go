@@ -58,9 +58,9 @@ func (sender *FlatSender) Send() *Sender {
 	}
 }
 
-func (sender *FlatSender) ImageBase64(file interface{}) *FlatSender {
+func (sender *FlatSender) ImageBase64(file interface{}, options ...cqcode.ImageOptions) *FlatSender {
 	n := clone(sender)
-	img, err := NewImageBase64(file)
+	img, err := cqcode.NewImageBase64(file, options...)
 	n.Err = err
 	if err == nil {
 		n.cache = append(n.cache, img)
@@ -70,7 +70,7 @@ func (sender *FlatSender) ImageBase64(file interface{}) *FlatSender {
 
 func (sender *Sender) RecordBase64(file interface{}, magic bool) *Sender {
 	n := clone(sender.FlatSender)
-	rec, err := NewRecordBase64(file)
+	rec, err := cqcode.NewRecordBase64(file)
 	n.Err = err
 	if err == nil {
 		rec.Magic = magic
@@ -83,7 +83,7 @@ func (sender *Sender) RecordBase64(file interface{}, magic bool) *Sender {
 // Please use ImageWeb instead.
 func (sender *FlatSender) ImageLocal(file string) *FlatSender {
 	n := clone(sender)
-	img := NewImageLocal(file)
+	img := cqcode.NewImageLocal(file)
 	n.cache = append(n.cache, img)
 	return n
 }
@@ -92,7 +92,7 @@ func (sender *FlatSender) ImageLocal(file string) *FlatSender {
 // Please use RecordWeb instead.
 func (sender *Sender) RecordLocal(file string, magic bool) *Sender {
 	n := clone(sender.FlatSender)
-	rec := NewRecordLocal(file)
+	rec := cqcode.NewRecordLocal(file)
 	rec.Magic = magic
 	n.cache = append(n.cache, rec)
 	return n.Send()
@@ -100,14 +100,14 @@ func (sender *Sender) RecordLocal(file string, magic bool) *Sender {
 
 func (sender *FlatSender) ImageWeb(url *url.URL) *FlatSender {
 	n := clone(sender)
-	img := NewImageWeb(url)
+	img := cqcode.NewImageWeb(url)
 	n.cache = append(n.cache, img)
 	return n
 }
 
 func (sender *Sender) RecordWeb(url *url.URL, magic bool) *Sender {
 	n := clone(sender.FlatSender)
-	rec := NewRecordWeb(url)
+	rec := cqcode.NewRecordWeb(url)
 	rec.Magic = magic
 	n.cache = append(n.cache, rec)
 	return n.Send()
@@ -213,8 +213,23 @@ func (sender *Sender) Shake(emojiID int) *Sender {
 	return n.Send()
 }
 
+// Poke pokes a user, which is the [CQ:poke] segment used by modern clients instead of shake.
+func (sender *Sender) Poke(userID int64) *Sender {
+	n := clone(sender.FlatSender)
+	t := cqcode.Poke{
+		QQ: userID,
+	}
+	n.cache = append(n.cache, &t)
+	return n.Send()
+}
+
+// Music sends a music, or returns a Sender with the error of music.Validate without sending.
 func (sender *Sender) Music(music cqcode.Music) *Sender {
 	n := clone(sender.FlatSender)
+	if err := music.Validate(); err != nil {
+		n.Err = err
+		return &Sender{FlatSender: n}
+	}
 	n.cache = append(n.cache, &music)
 	return n.Send()
 }
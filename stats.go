@@ -0,0 +1,121 @@
+package qqbotapi
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats are the runtime counters of a bot.
+type Stats struct {
+	MessagesSent      int64 // messages sent successfully
+	MessagesReceived  int64 // updates of messages
+	UpdatesReceived   int64 // updates of all post types
+	APICalls          int64
+	APIErrors         int64
+	PendingWSRequests int   // API requests over websocket waiting for responses
+	ChannelDepth      int   // updates buffered in update channels
+	Reconnects        int64 // retries of polling and reverse websocket connections after the first one
+	StoreHits         int64 // quoted messages found in MessageStore
+	StoreMisses       int64 // quoted messages fetched with get_msg
+}
+
+// StoreHitRate returns the rate of quoted messages found in MessageStore.
+func (s Stats) StoreHitRate() float64 {
+	if s.StoreHits+s.StoreMisses == 0 {
+		return 0
+	}
+	return float64(s.StoreHits) / float64(s.StoreHits+s.StoreMisses)
+}
+
+// botStats holds the counters of a bot, which are updated atomically.
+type botStats struct {
+	messagesSent     int64
+	messagesReceived int64
+	updatesReceived  int64
+	apiCalls         int64
+	apiErrors        int64
+	reconnects       int64
+	storeHits        int64
+	storeMisses      int64
+	wsConnections    int64
+
+	mux      sync.Mutex
+	channels []chan Update
+}
+
+// counters returns the counters of the bot, allocated on first use
+// to keep the int64 fields aligned for atomic operations.
+func (bot *BotAPI) counters() *botStats {
+	bot.statsOnce.Do(func() {
+		bot.stats = &botStats{}
+	})
+	return bot.stats
+}
+
+// trackChannel adds an update channel, whose depth is reported by Stats.
+func (bot *BotAPI) trackChannel(ch chan Update) {
+	s := bot.counters()
+	s.mux.Lock()
+	s.channels = append(s.channels, ch)
+	s.mux.Unlock()
+}
+
+// untrackChannel removes an update channel added by trackChannel, once nothing is sent to it.
+func (bot *BotAPI) untrackChannel(ch chan Update) {
+	s := bot.counters()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i, c := range s.channels {
+		if c == ch {
+			s.channels = append(s.channels[:i], s.channels[i+1:]...)
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the runtime counters of the bot.
+func (bot *BotAPI) Stats() Stats {
+	s := bot.counters()
+	stats := Stats{
+		MessagesSent:     atomic.LoadInt64(&s.messagesSent),
+		MessagesReceived: atomic.LoadInt64(&s.messagesReceived),
+		UpdatesReceived:  atomic.LoadInt64(&s.updatesReceived),
+		APICalls:         atomic.LoadInt64(&s.apiCalls),
+		APIErrors:        atomic.LoadInt64(&s.apiErrors),
+		Reconnects:       atomic.LoadInt64(&s.reconnects),
+		StoreHits:        atomic.LoadInt64(&s.storeHits),
+		StoreMisses:      atomic.LoadInt64(&s.storeMisses),
+	}
+	s.mux.Lock()
+	for _, ch := range s.channels {
+		stats.ChannelDepth += len(ch)
+	}
+	s.mux.Unlock()
+	stats.PendingWSRequests = bot.tracker().len()
+	return stats
+}
+
+// StatsHandler returns a http handler responding the stats of the bot in JSON,
+// for live inspection, e.g.
+//
+//	http.Handle("/debug/bot", bot.StatsHandler())
+func (bot *BotAPI) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := json.Marshal(bot.Stats())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
+
+// PublishStats publishes the stats of the bot as an expvar variable of name,
+// which is served at /debug/vars by expvar.
+//
+// It panics if the name is already registered, like expvar.Publish.
+func (bot *BotAPI) PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return bot.Stats()
+	}))
+}
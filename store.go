@@ -0,0 +1,177 @@
+package qqbotapi
+
+import (
+	"sync"
+)
+
+// MessageStore keeps messages the bot has seen, so that they can be looked up by
+// MessageID without another request, e.g. when resolving a quoted message.
+type MessageStore interface {
+	// Put saves a message.
+	Put(message *Message) error
+	// Get returns the message with the MessageID, or nil if it is not found.
+	Get(messageID int64) (*Message, error)
+}
+
+// MemoryMessageStore is a MessageStore in memory, which only keeps
+// a limited number of the latest messages.
+type MemoryMessageStore struct {
+	capacity int
+	messages map[int64]*Message
+	order    []int64
+	next     int
+	mux      sync.Mutex
+}
+
+// NewMemoryMessageStore creates a MemoryMessageStore keeping at most capacity messages.
+func NewMemoryMessageStore(capacity int) *MemoryMessageStore {
+	return &MemoryMessageStore{
+		capacity: capacity,
+		messages: make(map[int64]*Message),
+		order:    make([]int64, 0, capacity),
+	}
+}
+
+// Put saves a message, the oldest message will be dropped if the store is full.
+func (s *MemoryMessageStore) Put(message *Message) error {
+	if s.capacity <= 0 {
+		return nil
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.messages[message.MessageID]; ok {
+		s.messages[message.MessageID] = message
+		return nil
+	}
+	if len(s.order) < s.capacity {
+		s.order = append(s.order, message.MessageID)
+	} else {
+		delete(s.messages, s.order[s.next])
+		s.order[s.next] = message.MessageID
+		s.next = (s.next + 1) % s.capacity
+	}
+	s.messages[message.MessageID] = message
+	return nil
+}
+
+// Get returns the message with the MessageID, or nil if it is not found.
+func (s *MemoryMessageStore) Get(messageID int64) (*Message, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.messages[messageID], nil
+}
+
+// LikeProgress is the progress of likes sent to a user by LikeDaily.
+type LikeProgress struct {
+	UserID    int64  `json:"user_id"`
+	Remaining int    `json:"remaining"`
+	LastDay   string `json:"last_day"` // the day likes were last sent, formatted as 2006-01-02
+}
+
+// LikeStore keeps the progress of LikeDaily, so that it continues after a restart.
+type LikeStore interface {
+	// PutLikeProgress saves the progress of a user, and deletes it if nothing remains.
+	PutLikeProgress(progress *LikeProgress) error
+	// GetLikeProgress returns the progress of a user, or nil if it is not found.
+	GetLikeProgress(userID int64) (*LikeProgress, error)
+	// LikeProgresses returns all the unfinished progresses.
+	LikeProgresses() ([]*LikeProgress, error)
+}
+
+// MemoryLikeStore is a LikeStore in memory.
+type MemoryLikeStore struct {
+	progresses map[int64]LikeProgress
+	mux        sync.Mutex
+}
+
+// NewMemoryLikeStore creates a MemoryLikeStore.
+func NewMemoryLikeStore() *MemoryLikeStore {
+	return &MemoryLikeStore{
+		progresses: make(map[int64]LikeProgress),
+	}
+}
+
+// PutLikeProgress saves the progress of a user, and deletes it if nothing remains.
+func (s *MemoryLikeStore) PutLikeProgress(progress *LikeProgress) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if progress.Remaining <= 0 {
+		delete(s.progresses, progress.UserID)
+		return nil
+	}
+	s.progresses[progress.UserID] = *progress
+	return nil
+}
+
+// GetLikeProgress returns the progress of a user, or nil if it is not found.
+func (s *MemoryLikeStore) GetLikeProgress(userID int64) (*LikeProgress, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	progress, ok := s.progresses[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &progress, nil
+}
+
+// LikeProgresses returns all the unfinished progresses.
+func (s *MemoryLikeStore) LikeProgresses() ([]*LikeProgress, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	progresses := make([]*LikeProgress, 0, len(s.progresses))
+	for _, progress := range s.progresses {
+		p := progress
+		progresses = append(progresses, &p)
+	}
+	return progresses, nil
+}
+
+// SuperuserStore keeps the superusers of the bot, who have RoleSuperuser everywhere.
+type SuperuserStore interface {
+	// IsSuperuser returns if the user is a superuser.
+	IsSuperuser(userID int64) (bool, error)
+	// PutSuperuser adds a superuser.
+	PutSuperuser(userID int64) error
+	// DeleteSuperuser removes a superuser.
+	DeleteSuperuser(userID int64) error
+}
+
+// MemorySuperuserStore is a SuperuserStore in memory.
+type MemorySuperuserStore struct {
+	superusers map[int64]bool
+	mux        sync.Mutex
+}
+
+// NewMemorySuperuserStore creates a MemorySuperuserStore with the superusers.
+func NewMemorySuperuserStore(superusers ...int64) *MemorySuperuserStore {
+	s := &MemorySuperuserStore{
+		superusers: make(map[int64]bool),
+	}
+	for _, userID := range superusers {
+		s.superusers[userID] = true
+	}
+	return s
+}
+
+// IsSuperuser returns if the user is a superuser.
+func (s *MemorySuperuserStore) IsSuperuser(userID int64) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.superusers[userID], nil
+}
+
+// PutSuperuser adds a superuser.
+func (s *MemorySuperuserStore) PutSuperuser(userID int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.superusers[userID] = true
+	return nil
+}
+
+// DeleteSuperuser removes a superuser.
+func (s *MemorySuperuserStore) DeleteSuperuser(userID int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.superusers, userID)
+	return nil
+}
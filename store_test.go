@@ -0,0 +1,73 @@
+package qqbotapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryMessageStore(t *testing.T) {
+	store := NewMemoryMessageStore(2)
+	for i := int64(1); i <= 3; i++ {
+		store.Put(&Message{MessageID: i})
+	}
+
+	m1, _ := store.Get(1)
+	m3, _ := store.Get(3)
+	if m1 == nil && m3 != nil && m3.MessageID == 3 {
+		t.Log("TestMemoryMessageStore passed")
+	} else {
+		t.Errorf("TestMemoryMessageStore failed: %v %v", m1, m3)
+	}
+}
+
+func TestLikeDaily(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	var liked int
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL, LikeStore: NewMemoryLikeStore()}
+	bot.ResponseHook = func(endpoint string, body []byte) {
+		liked++
+	}
+	now := time.Date(2018, 1, 1, 12, 0, 0, 0, LikeLocation)
+	timeNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	bot.LikeStore.PutLikeProgress(&LikeProgress{UserID: 10000, Remaining: 25})
+	remaining, err := bot.likeToday(10000)
+	remaining2, err2 := bot.likeToday(10000)
+	progress, _ := bot.LikeStore.GetLikeProgress(10000)
+	wait := untilNextDay(now)
+	if err == nil && err2 == nil && liked == 1 && remaining == 15 && remaining2 == 15 && progress.LastDay == "2018-01-01" && wait == 12*time.Hour+time.Minute {
+		t.Log("TestLikeDaily passed")
+	} else {
+		t.Errorf("TestLikeDaily failed: %v %v %v %v %v %+v %v", err, err2, liked, remaining, remaining2, progress, wait)
+	}
+}
+
+func TestLikeDaily_Stop(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	err := bot.LikeDaily(10000, 25)
+	bot.StopReceivingUpdates()
+
+	running := true
+	for i := 0; i < 100 && running; i++ {
+		time.Sleep(10 * time.Millisecond)
+		bot.likeMux.Lock()
+		running = bot.likeRunning[10000]
+		bot.likeMux.Unlock()
+	}
+	progress, _ := bot.LikeStore.GetLikeProgress(10000)
+	if err == nil && !running && progress.Remaining == 15 {
+		t.Log("TestLikeDaily_Stop passed")
+	} else {
+		t.Errorf("TestLikeDaily_Stop failed: %v %v %+v", err, running, progress)
+	}
+}
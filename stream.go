@@ -0,0 +1,154 @@
+package qqbotapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// StreamGroupMemberList fetches a group all member's info, like GetGroupMemberList,
+// but decodes the response incrementally and calls fn for each member instead of
+// holding all of them in memory. Decoding stops if fn returns an error.
+//
+// The whole response is still kept in memory over WebSocket.
+func (bot *BotAPI) StreamGroupMemberList(groupID int64, fn func(member GroupMember) error) error {
+	return bot.StreamGroupMemberListWithContext(context.Background(), groupID, fn)
+}
+
+// StreamGroupMemberListWithContext streams the members like StreamGroupMemberList,
+// which is aborted when ctx is done.
+func (bot *BotAPI) StreamGroupMemberListWithContext(ctx context.Context, groupID int64, fn func(member GroupMember) error) error {
+	return bot.streamRequest(ctx, "get_group_member_list", Params{"group_id": groupID}, func(dec *json.Decoder) error {
+		var member GroupMember
+		if err := dec.Decode(&member); err != nil {
+			return err
+		}
+		return fn(member)
+	})
+}
+
+// streamKey is the key of the function consuming a streamed response in the context of a request.
+type streamKey struct{}
+
+// streamRequest makes a request whose data is an array through the middlewares like
+// MakeRequestWithParams, and calls each with the decoder positioned at every element.
+//
+// The middlewares get the response without data, which is consumed by each.
+func (bot *BotAPI) streamRequest(ctx context.Context, endpoint string, params Params, each func(dec *json.Decoder) error) error {
+	_, err := bot.MakeRequestWithParams(context.WithValue(ctx, streamKey{}, each), endpoint, params)
+	return err
+}
+
+// makeStreamRequest makes a request over HTTP or websocket whose data is consumed by each.
+func (bot *BotAPI) makeStreamRequest(ctx context.Context, endpoint string, params Params, each func(dec *json.Decoder) error) (APIResponse, error) {
+	if bot.Client == nil {
+		resp, err := bot.makeWSRequest(ctx, endpoint, params)
+		if err != nil {
+			return resp, err
+		}
+		data := resp.Data
+		resp.Data = nil
+		return resp, decodeArray(json.NewDecoder(bytes.NewReader(data)), each)
+	}
+
+	reqBody, err := params.body()
+	if err != nil {
+		return APIResponse{}, err
+	}
+	body, err := bot.openHTTPRequest(ctx, endpoint, reqBody)
+	if err != nil {
+		return APIResponse{}, err
+	}
+	defer body.Close()
+	return decodeAPIResponseStream(json.NewDecoder(body), each)
+}
+
+// streamOf returns the function consuming the response of a request streamed by streamRequest.
+func streamOf(ctx context.Context) (func(dec *json.Decoder) error, bool) {
+	each, ok := ctx.Value(streamKey{}).(func(dec *json.Decoder) error)
+	return each, ok
+}
+
+// decodeAPIResponseStream walks through the tokens of an APIResponse,
+// decoding the array in "data" element by element.
+//
+// The data is only streamed if "status" comes first, or else it is kept until "status"
+// is known, so that each is never called with the data of a failed response.
+func decodeAPIResponseStream(dec *json.Decoder, each func(dec *json.Decoder) error) (APIResponse, error) {
+	var apiResp APIResponse
+	if err := expectDelim(dec, '{'); err != nil {
+		return apiResp, err
+	}
+	var data json.RawMessage
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return apiResp, err
+		}
+		switch t {
+		case "status":
+			err = dec.Decode(&apiResp.Status)
+		case "retcode":
+			err = dec.Decode(&apiResp.RetCode)
+		case "msg":
+			err = dec.Decode(&apiResp.Msg)
+		case "wording":
+			err = dec.Decode(&apiResp.Wording)
+		case "data":
+			if apiResp.Status == "ok" {
+				err = decodeArray(dec, each)
+			} else {
+				err = dec.Decode(&data)
+			}
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return apiResp, err
+		}
+	}
+	if err := checkAPIResponse(apiResp); err != nil {
+		return apiResp, err
+	}
+	if data != nil {
+		return apiResp, decodeArray(json.NewDecoder(bytes.NewReader(data)), each)
+	}
+	return apiResp, nil
+}
+
+// decodeArray decodes an array element by element, null is regarded as an empty array.
+func decodeArray(dec *json.Decoder, each func(dec *json.Decoder) error) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	if t != json.Delim('[') {
+		return errors.New("unexpected json token, expecting an array")
+	}
+	for dec.More() {
+		if err := each(dec); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return errors.New("unexpected json token, expecting " + delim.String())
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package qqbotapi
+
+import (
+	"sort"
+	"sync"
+)
+
+// requestTracker keeps the requests over websocket waiting for responses,
+// matched by their echo. All of its methods are safe for concurrent use.
+type requestTracker struct {
+	mux     sync.Mutex
+	echo    int
+	pending map[int]*pendingRequest
+}
+
+// pendingRequest is a request waiting for its response.
+type pendingRequest struct {
+	req WebSocketRequest
+	ch  chan wsResult
+}
+
+// wsResult is the response of a request, or the error failing it without a response.
+type wsResult struct {
+	resp APIResponse
+	err  error
+}
+
+// newRequestTracker creates an empty requestTracker.
+func newRequestTracker() *requestTracker {
+	return &requestTracker{
+		pending: make(map[int]*pendingRequest),
+	}
+}
+
+// add assigns a new echo to a request of action, and returns the request and
+// the channel receiving its result.
+//
+// The channel is buffered and never closed, so that resolving never blocks,
+// and a request given up only has to be removed.
+func (t *requestTracker) add(action string, params Params) (WebSocketRequest, <-chan wsResult) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.echo++
+	req := WebSocketRequest{
+		Echo:   t.echo,
+		Action: action,
+		Params: params,
+	}
+	ch := make(chan wsResult, 1)
+	t.pending[t.echo] = &pendingRequest{req: req, ch: ch}
+	return req, ch
+}
+
+// resolve delivers resp to the request of echo, and reports whether it is pending.
+func (t *requestTracker) resolve(echo int, resp APIResponse) bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	p, ok := t.pending[echo]
+	if !ok {
+		return false
+	}
+	delete(t.pending, echo)
+	p.ch <- wsResult{resp: resp}
+	return true
+}
+
+// failUnless fails the pending requests with err unless keep returns true for them,
+// and returns the requests kept in order of their echo.
+func (t *requestTracker) failUnless(keep func(req WebSocketRequest) bool, err error) []WebSocketRequest {
+	var kept []WebSocketRequest
+	for _, req := range t.requests() {
+		if keep(req) {
+			kept = append(kept, req)
+			continue
+		}
+		t.mux.Lock()
+		if p, ok := t.pending[req.Echo.(int)]; ok {
+			delete(t.pending, req.Echo.(int))
+			p.ch <- wsResult{err: err}
+		}
+		t.mux.Unlock()
+	}
+	return kept
+}
+
+// remove stops tracking the request of echo.
+func (t *requestTracker) remove(echo int) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.pending, echo)
+}
+
+// requests returns the pending requests in order of their echo.
+func (t *requestTracker) requests() []WebSocketRequest {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	echoes := make([]int, 0, len(t.pending))
+	for echo := range t.pending {
+		echoes = append(echoes, echo)
+	}
+	sort.Ints(echoes)
+	reqs := make([]WebSocketRequest, 0, len(echoes))
+	for _, echo := range echoes {
+		reqs = append(reqs, t.pending[echo].req)
+	}
+	return reqs
+}
+
+// len returns the number of pending requests.
+func (t *requestTracker) len() int {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return len(t.pending)
+}
+
+// tracker returns the requestTracker of the bot, allocated on first use.
+func (bot *BotAPI) tracker() *requestTracker {
+	bot.trackerOnce.Do(func() {
+		bot.requests = newRequestTracker()
+	})
+	return bot.requests
+}
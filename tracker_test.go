@@ -0,0 +1,65 @@
+package qqbotapi
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRequestTracker(t *testing.T) {
+	tracker := newRequestTracker()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, ch := tracker.add("get_status", nil)
+			echo := req.Echo.(int)
+			if i%2 == 0 {
+				tracker.remove(echo)
+				tracker.resolve(echo, APIResponse{Echo: echo})
+				return
+			}
+			go tracker.resolve(echo, APIResponse{Echo: echo})
+			if res := <-ch; res.resp.Echo != echo {
+				t.Errorf("TestRequestTracker failed: response %v for echo %d", res.resp.Echo, echo)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if tracker.len() == 0 && tracker.echo == 100 {
+		t.Log("TestRequestTracker passed")
+	} else {
+		t.Errorf("TestRequestTracker failed: %d pending, echo %d", tracker.len(), tracker.echo)
+	}
+}
+
+func TestRequestTracker_Requests(t *testing.T) {
+	tracker := newRequestTracker()
+	tracker.add("a", nil)
+	req, _ := tracker.add("b", nil)
+	tracker.add("c", nil)
+	tracker.remove(req.Echo.(int))
+	reqs := tracker.requests()
+	if len(reqs) == 2 && reqs[0].Action == "a" && reqs[1].Action == "c" && !tracker.resolve(req.Echo.(int), APIResponse{}) {
+		t.Log("TestRequestTracker_Requests passed")
+	} else {
+		t.Errorf("TestRequestTracker_Requests failed: %v", reqs)
+	}
+}
+
+func TestRequestTracker_FailUnless(t *testing.T) {
+	tracker := newRequestTracker()
+	_, get := tracker.add("get_status", nil)
+	_, send := tracker.add("send_msg", nil)
+	kept := tracker.failUnless(isReplayableAction, ErrRequestLost)
+	var res wsResult
+	select {
+	case res = <-send:
+	default:
+	}
+	if len(kept) == 1 && kept[0].Action == "get_status" && res.err == ErrRequestLost && len(get) == 0 && tracker.len() == 1 {
+		t.Log("TestRequestTracker_FailUnless passed")
+	} else {
+		t.Errorf("TestRequestTracker_FailUnless failed: kept %v, result %v", kept, res)
+	}
+}
@@ -0,0 +1,117 @@
+package qqbotapi
+
+import (
+	"github.com/catsworld/qq-bot-api/cqcode"
+)
+
+// MessageTransformer transforms a message before it is sent to a chat.
+type MessageTransformer func(chat BaseChat, message cqcode.Message) cqcode.Message
+
+// UseMessageTransformer adds transformers, which are applied in order
+// to every message sent with MessageConfig, PrivateMessageConfig or GroupMessageConfig.
+// It is safe to call while messages are sent, which skip the transformers added meanwhile.
+func (bot *BotAPI) UseMessageTransformer(transformers ...MessageTransformer) {
+	bot.useMux.Lock()
+	defer bot.useMux.Unlock()
+	bot.transformers = append(bot.transformers[:len(bot.transformers):len(bot.transformers)], transformers...)
+}
+
+// transformMessage applies transformers of the bot to a MessageConfig.
+func (bot *BotAPI) transformMessage(config MessageConfig) MessageConfig {
+	bot.useMux.RLock()
+	transformers := bot.transformers
+	bot.useMux.RUnlock()
+	if len(transformers) == 0 {
+		return config
+	}
+	var message cqcode.Message
+	if config.AutoEscape {
+		message = cqcode.Message{&cqcode.Text{Text: config.Text}}
+		config.AutoEscape = false
+	} else {
+		message, _ = cqcode.ParseMessageFromString(config.Text)
+	}
+	for _, transform := range transformers {
+		message = transform(config.BaseChat, message)
+	}
+	config.Text = message.CQString()
+	return config
+}
+
+// AutoEscape returns a MessageTransformer which sends every segment
+// as literal text, except text and the allowed types,
+// e.g. CQ codes coming from untrusted user input.
+func AutoEscape(allowed ...string) MessageTransformer {
+	return func(chat BaseChat, message cqcode.Message) cqcode.Message {
+		m := cqcode.NewMessage()
+		for _, media := range message {
+			if media.FunctionName() == "text" || containsString(allowed, media.FunctionName()) {
+				m = append(m, media)
+				continue
+			}
+			m = append(m, &cqcode.Text{Text: cqcode.FormatCQCode(media)})
+		}
+		return m
+	}
+}
+
+// StripSegments returns a MessageTransformer which removes segments
+// of the types disallowed in a chat.
+func StripSegments(disallowed func(chat BaseChat) []string) MessageTransformer {
+	return func(chat BaseChat, message cqcode.Message) cqcode.Message {
+		types := disallowed(chat)
+		m := cqcode.NewMessage()
+		for _, media := range message {
+			if !containsString(types, media.FunctionName()) {
+				m = append(m, media)
+			}
+		}
+		return m
+	}
+}
+
+// AppendAttribution returns a MessageTransformer which appends a line
+// to the end of every message.
+func AppendAttribution(line string) MessageTransformer {
+	return func(chat BaseChat, message cqcode.Message) cqcode.Message {
+		return append(message, &cqcode.Text{Text: "\n" + line})
+	}
+}
+
+// DefaultPlaceholders are text placeholders of segments
+// which are not supported by older backends.
+var DefaultPlaceholders = map[string]string{
+	"poke":    "[戳一戳]",
+	"forward": "[合并转发]",
+	"node":    "[合并转发]",
+	"xml":     "[卡片消息]",
+	"json":    "[卡片消息]",
+	"redbag":  "[红包]",
+	"gift":    "[礼物]",
+	"contact": "[推荐联系人]",
+}
+
+// Placeholders returns a MessageTransformer which converts segments
+// of the types in placeholders to text, e.g. DefaultPlaceholders.
+func Placeholders(placeholders map[string]string) MessageTransformer {
+	return func(chat BaseChat, message cqcode.Message) cqcode.Message {
+		m := cqcode.NewMessage()
+		for _, media := range message {
+			if text, ok := placeholders[media.FunctionName()]; ok {
+				m = append(m, &cqcode.Text{Text: text})
+				continue
+			}
+			m = append(m, media)
+		}
+		return m
+	}
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,11 @@
 package qqbotapi
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/catsworld/qq-bot-api/cqcode"
 	"strconv"
+	"time"
 )
 
 // APIResponse is a response from the Coolq HTTP API with the result
@@ -12,6 +14,8 @@ type APIResponse struct {
 	Status  string          `json:"status"`
 	Data    json.RawMessage `json:"data"`
 	RetCode int             `json:"retcode"`
+	Msg     string          `json:"msg"`     // error code provided by go-cqhttp
+	Wording string          `json:"wording"` // error description provided by go-cqhttp
 	Echo    interface{}     `json:"echo"`
 }
 
@@ -24,6 +28,7 @@ type WebSocketRequest struct {
 // Update is an update response, from GetUpdates.
 type Update struct {
 	Time          int64       `json:"time"`
+	SelfID        int64       `json:"self_id"` // the account of the bot reporting the update
 	PostType      string      `json:"post_type"`
 	MessageType   string      `json:"message_type"`
 	SubType       string      `json:"sub_type"`
@@ -41,10 +46,307 @@ type Update struct {
 	File          *File       `json:"file"`
 	RequestType   string      `json:"request_type"`
 	Flag          string      `json:"flag"`
-	Comment       string      `json:"comment"` // This field is used for Request Event
-	Text          string      `json:"-"` // Message with CQCode
-	Message       *Message    `json:"-"` // Message parsed
+	Comment       string      `json:"comment"`         // This field is used for Request Event
+	InvitorID     int64       `json:"invitor_id"`      // This field is used for Request Event
+	MetaEventType string      `json:"meta_event_type"` // "lifecycle"、"heartbeat"
+	Status        *Status     `json:"status"`          // This field is used for Heartbeat Meta Event
+	Interval      int64       `json:"interval"`        // This field is used for Heartbeat Meta Event, in milliseconds
+	CardNew       string      `json:"card_new"`        // This field is used for Group Card Notice
+	CardOld       string      `json:"card_old"`        // This field is used for Group Card Notice
+	Client        *Device     `json:"client"`          // This field is used for Client Status Notice
+	Online        bool        `json:"online"`          // This field is used for Client Status Notice
+	SenderID      int64       `json:"sender_id"`       // This field is used for Essence Notice
+	GuildID       string      `json:"guild_id"`        // This field is used for Guild Message
+	ChannelID     string      `json:"channel_id"`      // This field is used for Guild Message
+	SelfTinyID    string      `json:"self_tiny_id"`    // This field is used for Guild Message
+	Text          string      `json:"-"`               // Message with CQCode
+	Message       *Message    `json:"-"`               // Message parsed
 	Sender        *User       `json:"sender"`
+
+	// GuildMessageID is the ID of a guild message, which is a string instead of MessageID.
+	GuildMessageID string `json:"-"`
+
+	ctx context.Context
+}
+
+// Context returns the context of the update, e.g. carrying a trace span
+// started by an Ev interceptor, or context.Background if it is not set.
+//
+// Pass it to the requests made for the update, e.g. SendWithContext,
+// so that they are traced as its children.
+func (update Update) Context() context.Context {
+	if update.ctx == nil {
+		return context.Background()
+	}
+	return update.ctx
+}
+
+// WithContext returns a copy of the update with ctx.
+func (update Update) WithContext(ctx context.Context) Update {
+	update.ctx = ctx
+	return update
+}
+
+// FriendRequest is a request of a user to add the bot as a friend.
+type FriendRequest struct {
+	UserID  int64
+	Comment string
+	Flag    string
+}
+
+// GroupRequest is a request about the bot and a group.
+type GroupRequest struct {
+	GroupID int64
+	UserID  int64
+	SubType string // "add"、"invite"
+	Comment string
+	Flag    string
+}
+
+// GroupJoinRequest is a request of a user to join a group managed by the bot.
+type GroupJoinRequest struct {
+	GroupRequest
+	InviterID int64 // the member inviting the user, 0 if the user applied on their own
+}
+
+// GroupInvite is an invitation for the bot to join a group.
+type GroupInvite struct {
+	GroupRequest
+	InviterID int64
+}
+
+// FriendRequest returns the friend request of a request update, or nil if it is not one.
+func (update Update) FriendRequest() *FriendRequest {
+	if update.PostType != "request" || update.RequestType != "friend" {
+		return nil
+	}
+	return &FriendRequest{
+		UserID:  update.UserID,
+		Comment: update.Comment,
+		Flag:    update.Flag,
+	}
+}
+
+// groupRequest returns the group request of a request update of subType, or nil if it is not one.
+func (update Update) groupRequest(subType string) *GroupRequest {
+	if update.PostType != "request" || update.RequestType != "group" || update.SubType != subType {
+		return nil
+	}
+	return &GroupRequest{
+		GroupID: update.GroupID,
+		UserID:  update.UserID,
+		SubType: update.SubType,
+		Comment: update.Comment,
+		Flag:    update.Flag,
+	}
+}
+
+// GroupJoinRequest returns the group join request of a request update, or nil if it is not one.
+func (update Update) GroupJoinRequest() *GroupJoinRequest {
+	r := update.groupRequest("add")
+	if r == nil {
+		return nil
+	}
+	return &GroupJoinRequest{
+		GroupRequest: *r,
+		InviterID:    update.InvitorID,
+	}
+}
+
+// GroupInvite returns the group invitation of a request update, or nil if it is not one.
+func (update Update) GroupInvite() *GroupInvite {
+	r := update.groupRequest("invite")
+	if r == nil {
+		return nil
+	}
+	return &GroupInvite{
+		GroupRequest: *r,
+		InviterID:    update.UserID,
+	}
+}
+
+// Lifecycle is a meta event reported when the implementation connects, is enabled or disabled.
+type Lifecycle struct {
+	SubType string // "connect"、"enable"、"disable"
+}
+
+// Heartbeat is a meta event reported by the implementation periodically, if enabled.
+type Heartbeat struct {
+	Status   Status
+	Interval time.Duration // until the next heartbeat
+}
+
+// Lifecycle returns the lifecycle of a meta event update, or nil if it is not one.
+func (update Update) Lifecycle() *Lifecycle {
+	if update.PostType != "meta_event" || update.MetaEventType != "lifecycle" {
+		return nil
+	}
+	return &Lifecycle{
+		SubType: update.SubType,
+	}
+}
+
+// Heartbeat returns the heartbeat of a meta event update, or nil if it is not one.
+func (update Update) Heartbeat() *Heartbeat {
+	if update.PostType != "meta_event" || update.MetaEventType != "heartbeat" {
+		return nil
+	}
+	heartbeat := &Heartbeat{
+		Interval: time.Duration(update.Interval) * time.Millisecond,
+	}
+	if update.Status != nil {
+		heartbeat.Status = *update.Status
+	}
+	return heartbeat
+}
+
+// OfflineFile is a file sent by a friend, which is reported by go-cqhttp in offline_file notices.
+type OfflineFile struct {
+	UserID int64
+	Name   string
+	Size   int64
+	URL    string
+}
+
+// OfflineFile returns the file of an offline_file notice update, or nil if it is not one.
+func (update Update) OfflineFile() *OfflineFile {
+	if update.PostType != "notice" || update.NoticeType != "offline_file" || update.File == nil {
+		return nil
+	}
+	return &OfflineFile{
+		UserID: update.UserID,
+		Name:   update.File.Name,
+		Size:   update.File.Size,
+		URL:    update.File.URL,
+	}
+}
+
+// GroupRecall is a message recalled in a group, reported in group_recall notices.
+type GroupRecall struct {
+	GroupID    int64
+	UserID     int64 // the sender of the message
+	OperatorID int64
+	MessageID  int64
+}
+
+// GroupRecall returns the recall of a group_recall notice update, or nil if it is not one.
+func (update Update) GroupRecall() *GroupRecall {
+	if update.PostType != "notice" || update.NoticeType != "group_recall" {
+		return nil
+	}
+	return &GroupRecall{
+		GroupID:    update.GroupID,
+		UserID:     update.UserID,
+		OperatorID: update.OperatorID,
+		MessageID:  update.MessageID,
+	}
+}
+
+// FriendRecall is a message recalled by a friend, reported in friend_recall notices.
+type FriendRecall struct {
+	UserID    int64
+	MessageID int64
+}
+
+// FriendRecall returns the recall of a friend_recall notice update, or nil if it is not one.
+func (update Update) FriendRecall() *FriendRecall {
+	if update.PostType != "notice" || update.NoticeType != "friend_recall" {
+		return nil
+	}
+	return &FriendRecall{
+		UserID:    update.UserID,
+		MessageID: update.MessageID,
+	}
+}
+
+// GroupCardChange is a change of the card of a group member, reported by go-cqhttp in group_card notices.
+type GroupCardChange struct {
+	GroupID int64
+	UserID  int64
+	CardOld string
+	CardNew string
+}
+
+// GroupCardChange returns the change of a group_card notice update, or nil if it is not one.
+func (update Update) GroupCardChange() *GroupCardChange {
+	if update.PostType != "notice" || update.NoticeType != "group_card" {
+		return nil
+	}
+	return &GroupCardChange{
+		GroupID: update.GroupID,
+		UserID:  update.UserID,
+		CardOld: update.CardOld,
+		CardNew: update.CardNew,
+	}
+}
+
+// EssenceChange is a message added to or removed from the essence messages of a group,
+// reported by go-cqhttp in essence notices.
+type EssenceChange struct {
+	SubType    string // "add"、"delete"
+	GroupID    int64
+	MessageID  int64
+	SenderID   int64 // the sender of the message
+	OperatorID int64
+}
+
+// EssenceChange returns the change of an essence notice update, or nil if it is not one.
+func (update Update) EssenceChange() *EssenceChange {
+	if update.PostType != "notice" || update.NoticeType != "essence" {
+		return nil
+	}
+	return &EssenceChange{
+		SubType:    update.SubType,
+		GroupID:    update.GroupID,
+		MessageID:  update.MessageID,
+		SenderID:   update.SenderID,
+		OperatorID: update.OperatorID,
+	}
+}
+
+// ClientStatus is a change of the online status of another device logged in as the bot,
+// reported by go-cqhttp in client_status notices.
+type ClientStatus struct {
+	Client Device
+	Online bool
+}
+
+// ClientStatus returns the status of a client_status notice update, or nil if it is not one.
+func (update Update) ClientStatus() *ClientStatus {
+	if update.PostType != "notice" || update.NoticeType != "client_status" || update.Client == nil {
+		return nil
+	}
+	return &ClientStatus{
+		Client: *update.Client,
+		Online: update.Online,
+	}
+}
+
+// GroupSystemMsg contains the pending group requests, from GetGroupSystemMsg.
+type GroupSystemMsg struct {
+	InvitedRequests []GroupSystemRequest `json:"invited_requests"`
+	JoinRequests    []GroupSystemRequest `json:"join_requests"`
+}
+
+// GroupSystemRequest is a pending invitation for the bot to join a group,
+// or a pending request of a user to join a group managed by the bot.
+type GroupSystemRequest struct {
+	RequestID     int64  `json:"request_id"`
+	SubType       string `json:"-"` // "add"、"invite"
+	InvitorID     int64  `json:"invitor_uin"`
+	InvitorNick   string `json:"invitor_nick"`
+	RequesterID   int64  `json:"requester_uin"`
+	RequesterNick string `json:"requester_nick"`
+	Message       string `json:"message"`
+	GroupID       int64  `json:"group_id"`
+	GroupName     string `json:"group_name"`
+	Checked       bool   `json:"checked"`
+	Actor         int64  `json:"actor"` // the admin who has handled the request
+}
+
+// Flag returns the flag to handle the request with HandleGroupRequest.
+func (r GroupSystemRequest) Flag() string {
+	return strconv.FormatInt(r.RequestID, 10)
 }
 
 // UpdatesChannel is the channel for getting updates.
@@ -56,6 +358,7 @@ type File struct {
 	Name  string `json:"name"`
 	Size  int64  `json:"size"`
 	BusID int64  `json:"busid"`
+	URL   string `json:"url"` // only in offline file notices
 }
 
 // User is a user on QQ.
@@ -65,6 +368,9 @@ type User struct {
 	Sex      string `json:"sex"` // "male"、"female"、"unknown"
 	Age      int    `json:"age"`
 	Area     string `json:"area"`
+	// Friend
+	Remark string `json:"remark"`
+	Source string `json:"source"` // how a unidirectional friend has added the bot
 	// Group member
 	Card                string `json:"card"`
 	CardChangeable      bool   `json:"card_changeable"`
@@ -80,10 +386,154 @@ type User struct {
 	AnonymousFlag       string `json:"anonymous_flag" anonymous:"flag"`
 }
 
+// GroupMember is a member of a group on QQ.
+type GroupMember struct {
+	GroupID             int64  `json:"group_id"`
+	UserID              int64  `json:"user_id"`
+	NickName            string `json:"nickname"`
+	Sex                 string `json:"sex"` // "male"、"female"、"unknown"
+	Age                 int    `json:"age"`
+	Area                string `json:"area"`
+	Card                string `json:"card"`
+	CardChangeable      bool   `json:"card_changeable"`
+	Title               string `json:"title"`
+	TitleExpireTimeUnix int64  `json:"title_expire_time"`
+	Level               string `json:"level"`
+	Role                string `json:"role"` // "owner"、"admin"、"member"
+	Unfriendly          bool   `json:"unfriendly"`
+	JoinTimeUnix        int64  `json:"join_time"`
+	LastSentTimeUnix    int64  `json:"last_sent_time"`
+	ShutUpTimestamp     int64  `json:"shut_up_timestamp"` // Unix time until when the member is banned
+}
+
+// User converts a GroupMember to a User.
+func (m GroupMember) User() User {
+	return User{
+		ID:                  m.UserID,
+		NickName:            m.NickName,
+		Sex:                 m.Sex,
+		Age:                 m.Age,
+		Area:                m.Area,
+		Card:                m.Card,
+		CardChangeable:      m.CardChangeable,
+		Title:               m.Title,
+		TitleExpireTimeUnix: m.TitleExpireTimeUnix,
+		Level:               m.Level,
+		Role:                m.Role,
+		Unfriendly:          m.Unfriendly,
+		JoinTimeUnix:        m.JoinTimeUnix,
+		LastSentTimeUnix:    m.LastSentTimeUnix,
+	}
+}
+
 // Group is a group on QQ.
 type Group struct {
-	ID   int64  `json:"group_id"`
-	Name string `json:"group_name"`
+	ID             int64  `json:"group_id"`
+	Name           string `json:"group_name"`
+	Memo           string `json:"group_memo"`
+	CreateTimeUnix int64  `json:"group_create_time"`
+	Level          int    `json:"group_level"`
+	MemberCount    int    `json:"member_count"`
+	MaxMemberCount int    `json:"max_member_count"`
+}
+
+// IsFull returns if the group has reached its max member count.
+//
+// It is always false if the counts are not populated.
+func (g Group) IsFull() bool {
+	return g.MaxMemberCount > 0 && g.MemberCount >= g.MaxMemberCount
+}
+
+// Status is the running status of CQ HTTP, from GetStatus.
+type Status struct {
+	Online bool `json:"online"`
+	Good   bool `json:"good"`
+	// Stat is only reported by go-cqhttp.
+	Stat StatusStat `json:"stat"`
+}
+
+// StatusStat contains the counters of go-cqhttp since it started.
+type StatusStat struct {
+	PacketReceived  int64 `json:"packet_received"`
+	PacketSent      int64 `json:"packet_sent"`
+	PacketLost      int64 `json:"packet_lost"`
+	MessageReceived int64 `json:"message_received"`
+	MessageSent     int64 `json:"message_sent"`
+	DisconnectTimes int64 `json:"disconnect_times"`
+	LostTimes       int64 `json:"lost_times"`
+	LastMessageTime int64 `json:"last_message_time"`
+}
+
+// VersionInfo is the version of the implementation, from GetVersionInfo.
+type VersionInfo struct {
+	AppName         string `json:"app_name"`
+	AppVersion      string `json:"app_version"`
+	ProtocolVersion string `json:"protocol_version"`
+}
+
+// IsGoCQHTTP returns if the implementation is go-cqhttp.
+func (v VersionInfo) IsGoCQHTTP() bool {
+	return v.AppName == "go-cqhttp"
+}
+
+// Credentials are used to call QQ web interfaces as the bot, from GetCredentials.
+type Credentials struct {
+	Cookies   string `json:"cookies"`
+	CSRFToken int64  `json:"csrf_token"`
+}
+
+// MediaFile is an image or a record saved by CQ HTTP, from GetImage or GetRecord.
+//
+// File is the local path on the host of CQ HTTP,
+// Size, FileName and URL are only reported by go-cqhttp for images.
+type MediaFile struct {
+	File     string `json:"file"`
+	Size     int64  `json:"size"`
+	FileName string `json:"filename"`
+	URL      string `json:"url"`
+}
+
+// AtAllRemain is the quota of @all in a group, from GetGroupAtAllRemain.
+type AtAllRemain struct {
+	CanAtAll bool `json:"can_at_all"`
+	// ForGroup is how many times @all could be used in the group today.
+	ForGroup int `json:"remain_at_all_count_for_group"`
+	// ForBot is how many times the bot could use @all in the group today.
+	ForBot int `json:"remain_at_all_count_for_uin"`
+}
+
+// GroupNotice is a notice (公告) of a group, from GetGroupNotices.
+type GroupNotice struct {
+	ID              string             `json:"notice_id"`
+	SenderID        int64              `json:"sender_id"`
+	PublishTimeUnix int64              `json:"publish_time"`
+	Message         GroupNoticeMessage `json:"message"`
+}
+
+// GroupNoticeMessage is the content of a GroupNotice.
+type GroupNoticeMessage struct {
+	Text   string             `json:"text"`
+	Images []GroupNoticeImage `json:"images"`
+}
+
+// GroupNoticeImage is an image in a GroupNotice.
+type GroupNoticeImage struct {
+	ID     string `json:"id"`
+	Width  string `json:"width"`
+	Height string `json:"height"`
+}
+
+// Device is a device logged in with the account of the bot, from GetOnlineClients.
+type Device struct {
+	AppID int64  `json:"app_id"`
+	Name  string `json:"device_name"`
+	Kind  string `json:"device_kind"`
+}
+
+// ModelShow is a variant of a device model shown with the online status, from GetModelShow.
+type ModelShow struct {
+	ModelShow string `json:"model_show"`
+	NeedPay   bool   `json:"need_pay"`
 }
 
 // String displays a simple text version of a user.
@@ -113,9 +563,11 @@ func (u *User) Name() string {
 
 // Chat contains information about the place a message was sent.
 type Chat struct {
-	ID      int64  `json:"id"`
-	Type    string `json:"type"`     // "private"、"group"、"discuss"
-	SubType string `json:"sub_type"` // (only when Type is "private") "friend"、"group"、"discuss"、"other"
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`                 // "private"、"group"、"discuss"、"guild"
+	SubType   string `json:"sub_type"`             // (only when Type is "private") "friend"、"group"、"discuss"、"other"
+	GuildID   string `json:"guild_id,omitempty"`   // only when Type is "guild"
+	ChannelID string `json:"channel_id,omitempty"` // only when Type is "guild"
 }
 
 // IsPrivate returns if the Chat is a private conversation.
@@ -133,6 +585,11 @@ func (c Chat) IsDiscuss() bool {
 	return c.Type == "discuss"
 }
 
+// IsGuild returns if the Chat is a channel of a guild.
+func (c Chat) IsGuild() bool {
+	return c.Type == ChatTypeGuild
+}
+
 // Message is returned by almost every request, and contains data about
 // almost anything.
 type Message struct {
@@ -143,6 +600,96 @@ type Message struct {
 	Text            string `json:"text"`
 	SubType         string `json:"sub_type"` // (only when Chat.Type is "group") "normal"、"anonymous"、"notice"
 	Font            int    `json:"font"`
+	Time            int64  `json:"time"`        // unix time when the message is sent
+	MessageSeq      int64  `json:"message_seq"` // only in message history, to fetch the messages before
+	// ReplyTo is the message replied to, which is resolved if BaseUpdateConfig.ResolveReply is set.
+	ReplyTo *Message `json:"-"`
+}
+
+// messageJSON mirrors Message with the embedded *cqcode.Message as a field,
+// so that its MarshalJSON and UnmarshalJSON are not promoted to Message.
+type messageJSON struct {
+	Message    *cqcode.Message `json:"message"`
+	MessageID  int64           `json:"message_id"`
+	From       *User           `json:"from"`
+	Chat       *Chat           `json:"chat"`
+	Text       string          `json:"text"`
+	SubType    string          `json:"sub_type"`
+	Font       int             `json:"font"`
+	Time       int64           `json:"time"`
+	MessageSeq int64           `json:"message_seq"`
+}
+
+// MarshalJSON encodes the message with its content as an array of segments.
+func (m Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(messageJSON{
+		Message:    m.Message,
+		MessageID:  m.MessageID,
+		From:       m.From,
+		Chat:       m.Chat,
+		Text:       m.Text,
+		SubType:    m.SubType,
+		Font:       m.Font,
+		Time:       m.Time,
+		MessageSeq: m.MessageSeq,
+	})
+}
+
+// UnmarshalJSON decodes the message, e.g. the response of send_msg.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var mj messageJSON
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return err
+	}
+	*m = Message{
+		Message:    mj.Message,
+		MessageID:  mj.MessageID,
+		From:       mj.From,
+		Chat:       mj.Chat,
+		Text:       mj.Text,
+		SubType:    mj.SubType,
+		Font:       mj.Font,
+		Time:       mj.Time,
+		MessageSeq: mj.MessageSeq,
+	}
+	return nil
+}
+
+// messageData is a message in API responses, e.g. get_msg.
+type messageData struct {
+	Time        int64       `json:"time"`
+	MessageID   int64       `json:"message_id"`
+	MessageSeq  int64       `json:"message_seq"`
+	MessageType string      `json:"message_type"`
+	SubType     string      `json:"sub_type"`
+	Font        int         `json:"font"`
+	GroupID     int64       `json:"group_id"`
+	Sender      User        `json:"sender"`
+	RawMessage  interface{} `json:"message"`
+}
+
+// message parses messageData to a Message.
+func (d messageData) message() Message {
+	chat := Chat{
+		ID:   d.Sender.ID,
+		Type: d.MessageType,
+	}
+	if chat.IsGroup() {
+		chat.ID = d.GroupID
+	}
+	message, _ := cqcode.ParseMessage(d.RawMessage)
+	sender := d.Sender
+	return Message{
+		Message:    &message,
+		MessageID:  d.MessageID,
+		From:       &sender,
+		Chat:       &chat,
+		Text:       message.CQString(),
+		SubType:    d.SubType,
+		Font:       d.Font,
+		Time:       d.Time,
+		MessageSeq: d.MessageSeq,
+	}
 }
 
 // IsAnonymous returns if a message is an anonymous message.
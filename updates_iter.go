@@ -0,0 +1,43 @@
+//go:build go1.23
+
+package qqbotapi
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// Updates returns an iterator of updates over long polling or websocket,
+// which stops when ctx is done, aborting the request in flight.
+//
+// Unlike GetUpdatesChan, no goroutine is left running after the loop returns early.
+//
+//	for update := range bot.Updates(ctx, u) {
+//		...
+//	}
+func (bot *BotAPI) Updates(ctx context.Context, config UpdateConfig) iter.Seq[Update] {
+	return func(yield func(Update) bool) {
+		for ctx.Err() == nil {
+			updates, err := bot.getUpdatesSafely(ctx, config)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				bot.logger().Warn("Failed to get updates, retrying in 3 seconds...", "error", err)
+				select {
+				case <-time.After(time.Second * 3):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			for _, update := range updates {
+				if !yield(update) {
+					return
+				}
+			}
+		}
+	}
+}
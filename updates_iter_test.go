@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package qqbotapi
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUpdates(t *testing.T) {
+	server := newTestServer([]map[string]interface{}{
+		{"post_type": "message", "message_type": "private", "user_id": 10000, "message": "1"},
+		{"post_type": "message", "message_type": "private", "user_id": 10000, "message": "2"},
+		{"post_type": "message", "message_type": "private", "user_id": 10000, "message": "3"},
+	})
+	defer server.Close()
+
+	bot := &BotAPI{Client: server.Client(), APIEndpoint: server.URL}
+	texts := ""
+	for update := range bot.Updates(context.Background(), NewUpdate(0)) {
+		texts += update.Text
+		if len(texts) == 5 {
+			break
+		}
+	}
+	if texts == "12312" {
+		t.Log("TestUpdates passed")
+	} else {
+		t.Errorf("TestUpdates failed: %v", texts)
+	}
+}
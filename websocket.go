@@ -0,0 +1,227 @@
+package qqbotapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/net/websocket"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRequestLost is returned by a request over websocket if the connection drops before its response,
+// and it is not resent after reconnecting because it is not safe to repeat, e.g. sending a message.
+var ErrRequestLost = errors.New("websocket connection lost before the response")
+
+// defaultWSMaxBackoff is the max interval between reconnecting attempts if BotAPI.WSMaxBackoff is not set.
+const defaultWSMaxBackoff = time.Minute
+
+// dialWS dials the websocket of path under bot.APIEndpoint, e.g. "/api/".
+func (bot *BotAPI) dialWS(path string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(bot.APIEndpoint+path, "http://localhost/")
+	if err != nil {
+		return nil, errors.New("invalid websocket address")
+	}
+	config.Header.Add("Authorization", fmt.Sprintf("Token %s", bot.Token))
+	return websocket.DialConfig(config)
+}
+
+// redialWS dials the websocket of path until it succeeds, backing off exponentially,
+// or returns ctx.Err() when ctx is done.
+func (bot *BotAPI) redialWS(ctx context.Context, path string) (*websocket.Conn, error) {
+	backoff := time.Second
+	for {
+		conn, err := bot.dialWS(path)
+		if err == nil {
+			atomic.AddInt64(&bot.counters().reconnects, 1)
+			bot.logger().Info("Redial " + path + " ws succeeded")
+			return conn, nil
+		}
+		bot.logger().Warn("Redial "+path+" ws failed", "error", err, "retry_in", backoff)
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+		backoff = nextWSBackoff(backoff, bot.WSMaxBackoff)
+	}
+}
+
+// nextWSBackoff doubles backoff, which is capped at max, or defaultWSMaxBackoff if max is 0.
+func nextWSBackoff(backoff, max time.Duration) time.Duration {
+	if max <= 0 {
+		max = defaultWSMaxBackoff
+	}
+	backoff *= 2
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+// isWSDecodeError reports whether err is caused by a malformed message
+// rather than a broken connection.
+func isWSDecodeError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// apiConn returns WSAPIClient, which is replaced by reconnectAPI.
+func (bot *BotAPI) apiConn() *websocket.Conn {
+	bot.wsMux.Lock()
+	defer bot.wsMux.Unlock()
+	return bot.WSAPIClient
+}
+
+// eventConn returns WSEventClient, which is replaced on reconnecting.
+func (bot *BotAPI) eventConn() *websocket.Conn {
+	bot.wsMux.Lock()
+	defer bot.wsMux.Unlock()
+	return bot.WSEventClient
+}
+
+// setWSConns replaces WSAPIClient and WSEventClient, which are the same connection
+// over the universal websocket.
+func (bot *BotAPI) setWSConns(api, event *websocket.Conn) {
+	bot.wsMux.Lock()
+	defer bot.wsMux.Unlock()
+	bot.WSAPIClient, bot.WSEventClient = api, event
+}
+
+// sendWS sends req over the /api/ websocket, or returns ctx.Err() if ctx is done first.
+//
+// It never waits for reconnecting, during which sending fails on the closed connection.
+func (bot *BotAPI) sendWS(ctx context.Context, req WebSocketRequest) error {
+	conn := bot.apiConn()
+	if conn == nil {
+		return errors.New("api websocket not connected")
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- websocket.JSON.Send(conn, req)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isReplayableAction reports whether a request of action only reads, so that it is safe
+// to resend if its response might be lost, e.g. get_status and .get_word_slices.
+func isReplayableAction(req WebSocketRequest) bool {
+	action := strings.TrimLeft(req.Action, "._")
+	return strings.HasPrefix(action, "get_") || strings.HasPrefix(action, "can_")
+}
+
+// wsMessage holds the fields telling events from API responses over the universal websocket.
+type wsMessage struct {
+	PostType string      `json:"post_type"`
+	Echo     interface{} `json:"echo"`
+}
+
+// receiveAPIResponses receives API responses from the /api/ websocket and delivers them
+// to the pending requests until the bot is closed. If the connection drops, it reconnects,
+// resending the pending requests which only read and failing the others with ErrRequestLost.
+//
+// Over the universal websocket, events are passed to bot.wsEvents, and dropped if it is full,
+// e.g. if GetUpdates is never called, so that API responses are still received.
+func (bot *BotAPI) receiveAPIResponses() {
+	ctx := bot.closeContext()
+	for ctx.Err() == nil {
+		bot.receiveAPIResponse(ctx)
+	}
+	bot.tracker().failUnless(func(req WebSocketRequest) bool { return false }, ErrRequestLost)
+}
+
+// receiveAPIResponse receives and delivers a message from the /api/ or universal websocket,
+// recovering from panics so that receiving goes on.
+func (bot *BotAPI) receiveAPIResponse(ctx context.Context) {
+	defer bot.recoverPanic("WS APIResponse")
+	var data json.RawMessage
+	if err := websocket.JSON.Receive(bot.apiConn(), &data); err != nil {
+		if isWSDecodeError(err) {
+			bot.debugLog("WS APIResponse", "failed to read apiresponse", err)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		bot.logger().Warn("WS APIResponse connection lost, reconnecting", "error", err)
+		bot.reconnectAPI(ctx)
+		return
+	}
+	bot.handleWSMessage(data)
+}
+
+// handleWSMessage delivers a message received from the /api/ or universal websocket,
+// which is either an API response or an event.
+func (bot *BotAPI) handleWSMessage(data json.RawMessage) {
+	if bot.WSUniversal {
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		if msg.PostType != "" && msg.Echo == nil {
+			var update Update
+			if err := json.Unmarshal(data, &update); err != nil {
+				bot.debugLog("WS Event", "failed to read event", err)
+				return
+			}
+			select {
+			case bot.wsEvents <- update:
+			default:
+				bot.logger().Warn("WS Event dropped, the events are not taken by GetUpdates", "buffer", cap(bot.wsEvents))
+			}
+			return
+		}
+	}
+	resp := APIResponse{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		bot.debugLog("WS APIResponse", "failed to read apiresponse", err)
+		return
+	}
+	echo, ok := resp.Echo.(float64)
+	if !ok {
+		return
+	}
+	bot.tracker().resolve(int(echo), resp)
+}
+
+// reconnectAPI replaces the dropped /api/ or universal websocket. The requests still waiting
+// for responses, which might be lost with the old connection, are resent if they only read,
+// or else failed with ErrRequestLost, since they might have been done already.
+//
+// Requests made while redialing fail on the closed connection instead of waiting.
+// Redialing stops when ctx is done, leaving the closed connection.
+func (bot *BotAPI) reconnectAPI(ctx context.Context) {
+	bot.apiConn().Close()
+	replay := bot.tracker().failUnless(isReplayableAction, ErrRequestLost)
+
+	path := "/api/"
+	if bot.WSUniversal {
+		path = "/"
+	}
+	conn, err := bot.redialWS(ctx, path)
+	if err != nil {
+		return
+	}
+	bot.wsMux.Lock()
+	bot.WSAPIClient = conn
+	if bot.WSUniversal {
+		bot.WSEventClient = conn
+	}
+	bot.wsMux.Unlock()
+
+	for _, req := range replay {
+		if err := websocket.JSON.Send(conn, req); err != nil {
+			bot.logger().Warn("WS APIResponse failed to resend", "action", req.Action, "error", err)
+		}
+	}
+}